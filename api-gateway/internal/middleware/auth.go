@@ -7,8 +7,8 @@ import (
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID, Idempotency-Key, Last-Event-ID, X-Correlation-ID")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -23,7 +23,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		// For this example, we'll just check if the X-User-ID header is present
 		userID := r.Header.Get("X-User-ID")
 		if userID == "" {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			WriteProblem(w, http.StatusUnauthorized, "unauthorized", "X-User-ID header is required")
 			return
 		}
 
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// CorrelationIDHeader связывает запрос клиента с событиями, которые он вызвал
+// в сервисах. Шлюз передаёт его сервисам, а те — в заголовки записей Kafka.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationID присваивает запросу correlation ID, если клиент его не передал.
+func CorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(CorrelationIDHeader) == "" {
+			r.Header.Set(CorrelationIDHeader, newCorrelationID())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -13,17 +13,50 @@ import (
 func NewRouter(cfg *config.Config) *mux.Router {
 	r := mux.NewRouter()
 	r.Use(middleware.CORS)
+	r.Use(middleware.CorrelationID)
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.WriteProblem(w, http.StatusNotFound, "route_not_found", "no route for "+r.URL.Path)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		middleware.WriteProblem(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed for "+r.URL.Path)
+	})
 	// r.Use(middleware.AuthMiddleware) // TODO: Включить после реализации аутентификации на фронтенде
 
 	// Прокси маршруты для Order Service
 	r.HandleFunc("/orders", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/orders/{order_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/orders/{order_id}/cancel", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/orders/{order_id}/history", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/orders/user/{user_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/users/{user_id}/orders", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
-	r.HandleFunc("/api/products", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet)
+	r.HandleFunc("/api/products", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/products/{product_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/api/carts/{user_id}/items", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/api/carts/{user_id}/items/{product_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/api/carts/{user_id}/checkout", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/categories", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/promo-codes", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/tax-rates", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/tax-rates/{tax_rate_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/api/webhooks", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/webhooks/{webhook_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodDelete, http.MethodOptions)
+	r.HandleFunc("/api/webhooks/{webhook_id}/enable", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/webhooks/{webhook_id}/deliveries", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/api/orders", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/api/orders/{order_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/cancel", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/reorder", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/history", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/returns", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/returns/{return_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/returns/{return_id}/approve", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/shipments", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/shipments/{shipment_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/shipments/{shipment_id}/ship", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/shipments/{shipment_id}/deliver", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
+	r.HandleFunc("/api/orders/{order_id}/shipments/{shipment_id}/return", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodPost, http.MethodOptions)
 	r.HandleFunc("/api/orders/user/{user_id}", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/orders/user/{user_id}/events", proxyHandler(cfg.OrderServiceURL)).Methods(http.MethodGet, http.MethodOptions)
 
 	// Прокси маршруты для Payment Service
 	r.HandleFunc("/api/payment/accounts", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodPost, http.MethodOptions)
@@ -32,6 +65,8 @@ func NewRouter(cfg *config.Config) *mux.Router {
 	r.HandleFunc("/api/payment/accounts/{user_id}/withdraw", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodPost, http.MethodOptions)
 	r.HandleFunc("/api/payment/users", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodPost, http.MethodGet, http.MethodOptions)
 	r.HandleFunc("/api/payment/users/{user_id}", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/payment/exchange-rates", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodGet, http.MethodOptions)
+	r.HandleFunc("/api/payment/exchange-rates/{base}/{quote}", proxyHandler(cfg.PaymentServiceURL)).Methods(http.MethodPut, http.MethodOptions)
 
 	return r
 }
@@ -43,8 +78,18 @@ func proxyHandler(target string) http.HandlerFunc {
 		// Map API Gateway paths to service paths
 		if strings.HasPrefix(path, "/api/payment") {
 			path = strings.TrimPrefix(path, "/api/payment")
-		} else if path == "/api/products" {
-			path = "/products"
+		} else if strings.HasPrefix(path, "/api/products") {
+			path = strings.Replace(path, "/api/products", "/products", 1)
+		} else if strings.HasPrefix(path, "/api/carts") {
+			path = strings.Replace(path, "/api/carts", "/carts", 1)
+		} else if strings.HasPrefix(path, "/api/categories") {
+			path = strings.Replace(path, "/api/categories", "/categories", 1)
+		} else if strings.HasPrefix(path, "/api/promo-codes") {
+			path = strings.Replace(path, "/api/promo-codes", "/promo-codes", 1)
+		} else if strings.HasPrefix(path, "/api/tax-rates") {
+			path = strings.Replace(path, "/api/tax-rates", "/tax-rates", 1)
+		} else if strings.HasPrefix(path, "/api/webhooks") {
+			path = strings.Replace(path, "/api/webhooks", "/webhooks", 1)
 		} else if strings.HasPrefix(path, "/api/orders") {
 			path = strings.Replace(path, "/api/orders", "/orders", 1)
 		}
@@ -54,9 +99,10 @@ func proxyHandler(target string) http.HandlerFunc {
 			url += "?" + r.URL.RawQuery
 		}
 
-		req, err := http.NewRequest(r.Method, url, r.Body)
+		// Запрос к сервису отменяется вместе с клиентским, что закрывает и SSE-потоки
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, url, r.Body)
 		if err != nil {
-			http.Error(w, "failed to create request", http.StatusInternalServerError)
+			middleware.WriteProblem(w, http.StatusInternalServerError, "internal_error", "failed to create request")
 			return
 		}
 		for k, v := range r.Header {
@@ -67,15 +113,15 @@ func proxyHandler(target string) http.HandlerFunc {
 
 		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			http.Error(w, "failed to proxy request: "+err.Error(), http.StatusBadGateway)
+			middleware.WriteProblem(w, http.StatusBadGateway, "upstream_unavailable", "failed to proxy request: "+err.Error())
 			return
 		}
 		defer resp.Body.Close()
 
 		// Устанавливаем CORS-заголовки всегда
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-User-ID, Idempotency-Key, Last-Event-ID, X-Correlation-ID")
 
 		for k, v := range resp.Header {
 			for _, vv := range v {
@@ -83,6 +129,32 @@ func proxyHandler(target string) http.HandlerFunc {
 			}
 		}
 		w.WriteHeader(resp.StatusCode)
+
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			streamBody(w, resp.Body)
+			return
+		}
 		io.Copy(w, resp.Body)
 	}
 }
+
+// streamBody copies a Server-Sent Events response, flushing every chunk
+// as soon as it arrives instead of buffering it.
+func streamBody(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -154,7 +154,7 @@ function Account() {
       const res = await fetch('/api/orders');
       if (!res.ok) throw new Error('Не удалось получить список заказов');
       const data = await res.json();
-      setOrders(Array.isArray(data) ? data : []);
+      setOrders(Array.isArray(data.orders) ? data.orders : []);
     } catch (err) {
       setOrdersError(err.message || 'Ошибка при получении заказов');
     } finally {
@@ -170,7 +170,7 @@ function Account() {
       const res = await fetch(`/api/orders/user/${orderSearchUserId}`);
       if (!res.ok) throw new Error('Не удалось получить заказы пользователя');
       const data = await res.json();
-      setOrders(Array.isArray(data) ? data : []);
+      setOrders(Array.isArray(data.orders) ? data.orders : []);
     } catch (err) {
       setOrdersError(err.message || 'Ошибка при получении заказов пользователя');
     } finally {
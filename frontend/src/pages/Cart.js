@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useEffect, useRef, useState } from 'react';
 import {
   Container,
   Typography,
@@ -26,6 +26,12 @@ function Cart() {
   const [error, setError] = useState('');
   const [userBalance, setUserBalance] = useState(null);
   const [checkingBalance, setCheckingBalance] = useState(false);
+  // Один ключ на попытку оформления: повторный запрос не создаст второй заказ
+  const checkoutKey = useRef(null);
+
+  useEffect(() => {
+    checkoutKey.current = null;
+  }, [items, userId]);
 
   const totalPrice = items.reduce((sum, item) => sum + item.price * item.quantity, 0);
 
@@ -74,17 +80,24 @@ function Cart() {
       })),
     };
 
+    if (!checkoutKey.current) {
+      checkoutKey.current = crypto.randomUUID();
+    }
+
     try {
       // Create the order - payment will be processed automatically via Kafka
       const orderRes = await fetch('/api/orders', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: {
+          'Content-Type': 'application/json',
+          'Idempotency-Key': checkoutKey.current,
+        },
         body: JSON.stringify(orderRequest),
       });
 
       if (!orderRes.ok) {
         const errData = await orderRes.json();
-        throw new Error(errData.message || 'Failed to create order.');
+        throw new Error(errData.error || errData.message || 'Failed to create order.');
       }
 
       const order = await orderRes.json();
@@ -35,7 +35,7 @@ function Home() {
           throw new Error(`Failed to fetch products: ${res.statusText}`);
         }
         const data = await res.json();
-        setProducts(data || []);
+        setProducts((data && data.items) || []);
       } catch (err) {
         setError(err.message || 'An unexpected error occurred');
       } finally {
@@ -11,61 +11,67 @@ import {
   TableRow,
   Chip,
   Box,
+  TextField,
+  Alert,
 } from '@mui/material';
-import axios from 'axios';
-import io from 'socket.io-client';
-
-const API_URL = process.env.REACT_APP_API_URL || 'http://localhost:8080';
 
 function Orders() {
+  const [userId, setUserId] = useState('');
   const [orders, setOrders] = useState([]);
-  const [socket, setSocket] = useState(null);
+  const [error, setError] = useState('');
 
   useEffect(() => {
-    // Fetch initial orders
+    if (!userId) {
+      setOrders([]);
+      return undefined;
+    }
+
+    let cancelled = false;
     const fetchOrders = async () => {
       try {
-        const response = await axios.get(`${API_URL}/orders`, {
-          headers: {
-            'X-User-ID': '1', // For demo purposes
-          },
-        });
-        setOrders(response.data);
-      } catch (error) {
-        console.error('Error fetching orders:', error);
+        const res = await fetch(`/api/orders/user/${userId}`);
+        if (!res.ok) throw new Error('Failed to fetch orders');
+        const data = await res.json();
+        if (!cancelled) {
+          setOrders(data.orders);
+          setError('');
+        }
+      } catch (err) {
+        if (!cancelled) setError(err.message);
       }
     };
 
     fetchOrders();
 
-    // Set up WebSocket connection
-    const newSocket = io(API_URL);
-    setSocket(newSocket);
-
-    newSocket.on('order_updated', (updatedOrder) => {
-      setOrders((prevOrders) =>
-        prevOrders.map((order) =>
-          order.id === updatedOrder.id ? updatedOrder : order
-        )
-      );
+    // Изменения статусов приходят по SSE; при переподключении браузер
+    // сам передаёт Last-Event-ID, и пропущенные события досылаются
+    const source = new EventSource(`/api/orders/user/${userId}/events`);
+    source.addEventListener('order_status', (event) => {
+      const change = JSON.parse(event.data);
+      setOrders((prevOrders) => {
+        if (!prevOrders.some((order) => order.id === change.order_id)) {
+          fetchOrders();
+          return prevOrders;
+        }
+        return prevOrders.map((order) =>
+          order.id === change.order_id ? { ...order, status: change.to_status } : order
+        );
+      });
     });
 
     return () => {
-      if (newSocket) {
-        newSocket.disconnect();
-      }
+      cancelled = true;
+      source.close();
     };
-  }, []);
+  }, [userId]);
 
   const getStatusColor = (status) => {
     switch (status) {
-      case 'pending':
-        return 'warning';
-      case 'processing':
+      case 'NEW':
         return 'info';
-      case 'completed':
+      case 'FINISHED':
         return 'success';
-      case 'failed':
+      case 'CANCELLED':
         return 'error';
       default:
         return 'default';
@@ -78,6 +84,17 @@ function Orders() {
         My Orders
       </Typography>
 
+      <Box mb={3}>
+        <TextField
+          label="User ID"
+          variant="outlined"
+          value={userId}
+          onChange={(e) => setUserId(e.target.value.trim())}
+          fullWidth
+        />
+      </Box>
+      {error && <Alert severity="error" sx={{ mb: 2 }}>{error}</Alert>}
+
       <TableContainer component={Paper}>
         <Table>
           <TableHead>
@@ -99,7 +116,7 @@ function Orders() {
                     size="small"
                   />
                 </TableCell>
-                <TableCell>${order.total.toFixed(2)}</TableCell>
+                <TableCell>{Number(order.total_amount).toFixed(2)} {order.currency}</TableCell>
                 <TableCell>
                   {new Date(order.created_at).toLocaleString()}
                 </TableCell>
@@ -123,4 +140,4 @@ function Orders() {
   );
 }
 
-export default Orders; 
\ No newline at end of file
+export default Orders;
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -13,12 +14,14 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
 	"github.com/mnntn/ecommerce-project/order-service/internal/kafka"
 	"github.com/mnntn/ecommerce-project/order-service/internal/migration"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
 	"github.com/mnntn/ecommerce-project/order-service/internal/repository/postgres"
 	"github.com/mnntn/ecommerce-project/order-service/internal/service"
 	httptransport "github.com/mnntn/ecommerce-project/order-service/internal/transport/http"
+	"github.com/mnntn/ecommerce-project/order-service/internal/webhook"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
 func main() {
@@ -56,21 +59,56 @@ func main() {
 	orderRepo := postgres.NewOrderRepository(db)
 	productRepo := postgres.NewProductRepository(db)
 	outboxRepo := postgres.NewOutboxRepository(db.DB)
+	idempotencyRepo := postgres.NewIdempotencyRepository(db)
+	cartRepo := postgres.NewCartRepository(db)
+	promoRepo := postgres.NewPromoRepository(db)
+	returnRepo := postgres.NewReturnRepository(db)
+	shipmentRepo := postgres.NewShipmentRepository(db)
+	webhookRepo := postgres.NewWebhookRepository(db)
+	taxRepo := postgres.NewTaxRepository(db)
+
+	allowInsecureWebhooks := boolFromEnv("WEBHOOK_ALLOW_INSECURE", false)
 
 	// Initialize service
-	appService := service.New(orderRepo, productRepo, producer, outboxRepo)
+	statusFeed := service.NewStatusFeed()
+	appService := service.New(orderRepo, productRepo, producer, outboxRepo, idempotencyRepo, cartRepo, promoRepo, returnRepo, shipmentRepo, webhookRepo, taxRepo, statusFeed, service.Config{
+		CancelGracePeriod: durationFromEnv("ORDER_CANCEL_GRACE_PERIOD", 30*time.Minute),
+		PaymentTimeout:    durationFromEnv("ORDER_PAYMENT_TIMEOUT", 30*time.Minute),
+		IdempotencyKeyTTL: durationFromEnv("ORDER_IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		TaxInclusive:      boolFromEnv("ORDER_PRICES_INCLUDE_TAX", false),
+		DefaultTaxCountry: strings.ToUpper(os.Getenv("ORDER_TAX_DEFAULT_COUNTRY")),
+		// Только для локальной разработки: http и адреса внутренней сети
+		AllowInsecureWebhooks: allowInsecureWebhooks,
+	})
 
 	// Initialize status processor
-	statusProcessor := service.NewStatusProcessor(orderRepo)
-
-	// OutboxProcessor для transactional outbox
-	outboxProcessor := outbox.NewOutboxProcessor(outboxRepo, producer)
-	outboxProcessor.Start()
+	statusProcessor := service.NewStatusProcessor(orderRepo, returnRepo, statusFeed)
 
 	// Start Kafka consumer for status updates
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Уведомления о новых сообщениях outbox; без них процессор только опрашивает таблицу
+	var outboxWakeups outbox.Wakeups
+	outboxListener, err := outbox.NewListener(dbConnString)
+	if err != nil {
+		log.Printf("Outbox listener unavailable, falling back to polling: %v", err)
+	} else {
+		defer outboxListener.Close()
+		outboxWakeups = outboxListener
+	}
+
+	// Процессор transactional outbox; реплики делят очередь через аренду сообщений
+	outbox.NewProcessor(outboxRepo, producer, outbox.Config{
+		BatchSize:    intFromEnv("OUTBOX_BATCH_SIZE", outbox.DefaultConfig.BatchSize),
+		Workers:      intFromEnv("OUTBOX_WORKERS", outbox.DefaultConfig.Workers),
+		Lease:        durationFromEnv("OUTBOX_LEASE", outbox.DefaultConfig.Lease),
+		PollInterval: durationFromEnv("OUTBOX_POLL_INTERVAL", outbox.DefaultConfig.PollInterval),
+		MaxAttempts:  intFromEnv("OUTBOX_MAX_ATTEMPTS", outbox.DefaultConfig.MaxAttempts),
+		BaseBackoff:  durationFromEnv("OUTBOX_BASE_BACKOFF", outbox.DefaultConfig.BaseBackoff),
+		MaxBackoff:   durationFromEnv("OUTBOX_MAX_BACKOFF", outbox.DefaultConfig.MaxBackoff),
+	}, outboxWakeups).Start(ctx)
+
 	go func() {
 		for {
 			select {
@@ -78,18 +116,62 @@ func main() {
 				log.Println("Kafka consumer stopped due to context cancellation")
 				return
 			default:
-				event, err := consumer.ReadOrderStatusUpdatedEvent(ctx)
+				event, err := consumer.ReadPaymentEvent(ctx)
 				if err != nil {
 					if ctx.Err() != nil {
 						log.Println("Kafka consumer stopped due to context cancellation")
 						return
 					}
-					log.Printf("Error reading order status updated event: %v", err)
+					log.Printf("Error reading payment event: %v", err)
 					continue
 				}
 
-				if err := statusProcessor.ProcessOrderStatusUpdated(ctx, event); err != nil {
-					log.Printf("Error processing order status update: %v", err)
+				eventCtx := domain.WithCorrelationID(ctx, event.CorrelationID)
+				if err := statusProcessor.ProcessPaymentEvent(eventCtx, event.Type, event.Payload); err != nil {
+					log.Printf("Error processing %s: %v", event.Type, err)
+				}
+			}
+		}
+	}()
+
+	// Доставка вебхуков партнёрам; события ставятся в очередь вместе с outbox
+	webhookConfig := webhook.DefaultConfig
+	webhookConfig.AllowPrivateNetworks = allowInsecureWebhooks
+	webhook.NewDispatcher(webhookRepo, webhookConfig).Start(ctx)
+
+	// Periodically cancel orders that were never paid
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := appService.ExpireUnpaidOrders(ctx)
+				if err != nil {
+					log.Printf("Error expiring unpaid orders: %v", err)
+				} else if expired > 0 {
+					log.Printf("Cancelled %d unpaid orders", expired)
+				}
+			}
+		}
+	}()
+
+	// Periodically purge expired idempotency keys
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := appService.PurgeExpiredIdempotencyKeys(ctx)
+				if err != nil {
+					log.Printf("Error purging expired idempotency keys: %v", err)
+				} else if deleted > 0 {
+					log.Printf("Purged %d expired idempotency keys", deleted)
 				}
 			}
 		}
@@ -129,3 +211,42 @@ func main() {
 
 	log.Println("Server gracefully stopped")
 }
+
+// durationFromEnv reads a time.Duration such as "30m" from the environment.
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// boolFromEnv reads a boolean such as "true" or "1" from the environment.
+func boolFromEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return b
+}
+
+// intFromEnv reads a positive integer from the environment.
+func intFromEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid %s: must be a positive integer", key)
+	}
+	return n
+}
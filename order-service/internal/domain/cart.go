@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+// MaxCartItemQuantity bounds the quantity of a single cart line.
+const MaxCartItemQuantity = 1000
+
+var (
+	ErrCartEmpty   = errors.New("cart is empty")
+	ErrCartChanged = errors.New("cart was modified during checkout")
+)
+
+// CartItem is a stored cart line joined with the current state of its product.
+type CartItem struct {
+	UserID            string         `db:"user_id"`
+	ProductID         int64          `db:"product_id"`
+	Quantity          int            `db:"quantity"`
+	ProductName       string         `db:"product_name"`
+	Price             money.Amount   `db:"price"`
+	Currency          money.Currency `db:"currency"`
+	AvailableQuantity int            `db:"available_quantity"`
+	ProductDeleted    bool           `db:"product_deleted"`
+	UpdatedAt         time.Time      `db:"updated_at"`
+}
+
+// CartLine is a cart item priced at the current product price.
+type CartLine struct {
+	ProductID         int64          `json:"product_id"`
+	Name              string         `json:"name"`
+	Quantity          int            `json:"quantity"`
+	UnitPrice         money.Amount   `json:"unit_price"`
+	Currency          money.Currency `json:"currency"`
+	LineTotal         money.Amount   `json:"line_total"`
+	AvailableQuantity int            `json:"available_quantity"`
+	// Problem explains why the line cannot be checked out as is.
+	Problem string `json:"problem,omitempty"`
+}
+
+// Cart is the priced content of a user's cart. Total covers only the lines
+// that can be checked out.
+type Cart struct {
+	UserID      string         `json:"user_id"`
+	Items       []CartLine     `json:"items"`
+	Total       money.Amount   `json:"total"`
+	Currency    money.Currency `json:"currency,omitempty"`
+	CanCheckout bool           `json:"can_checkout"`
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+const (
+	DefaultProductPageSize = 20
+	MaxProductPageSize     = 100
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+// Category is a node of the hierarchical product catalog.
+type Category struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	ParentID  *int64    `json:"parent_id" db:"parent_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ProductSort defines the ordering of a product listing.
+type ProductSort string
+
+const (
+	// SortRelevance orders by full-text rank; without a query it falls back to catalog order.
+	SortRelevance ProductSort = "relevance"
+	SortPriceAsc  ProductSort = "price_asc"
+	SortPriceDesc ProductSort = "price_desc"
+	SortNewest    ProductSort = "newest"
+)
+
+// ProductFilter describes a product search request. Nil and empty fields are not applied.
+type ProductFilter struct {
+	Query string
+	// CategoryID matches products of the category and all of its subcategories.
+	CategoryID *int64
+	// Currency restricts the listing to prices in one currency, which makes
+	// price bounds and price ordering meaningful.
+	Currency money.Currency
+	MinPrice *money.Amount
+	MaxPrice *money.Amount
+	Sort     ProductSort
+	Limit    int
+	Offset   int
+}
+
+// CategoryFacet is the number of matching products in a category, subcategories included.
+type CategoryFacet struct {
+	CategoryID int64  `json:"category_id"`
+	ParentID   *int64 `json:"parent_id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	Count      int    `json:"count"`
+}
+
+// ProductSearchResult is a page of a product search with category facets.
+// Facets are computed without the category filter so that the storefront
+// can show how many products each category would yield.
+type ProductSearchResult struct {
+	Items  []*Product      `json:"items"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+	Facets []CategoryFacet `json:"facets"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the ID that ties together the
+// HTTP request and the events it causes across services.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of ctx, or "" if there is none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
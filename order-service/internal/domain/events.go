@@ -1,12 +1,24 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
 
 // OrderCreatedEvent is published when a new order is successfully created.
+// TotalAmount is the amount to charge, i.e. the subtotal less discounts,
+// plus TaxAmount unless TaxInclusive.
 type OrderCreatedEvent struct {
-	OrderID     uuid.UUID `json:"order_id"`
-	UserID      string    `json:"user_id"`
-	TotalAmount float64   `json:"total_amount"`
+	OrderID        uuid.UUID      `json:"order_id"`
+	UserID         string         `json:"user_id"`
+	SubtotalAmount money.Amount   `json:"subtotal_amount"`
+	DiscountAmount money.Amount   `json:"discount_amount"`
+	TaxAmount      money.Amount   `json:"tax_amount"`
+	TaxInclusive   bool           `json:"tax_inclusive"`
+	TotalAmount    money.Amount   `json:"total_amount"`
+	Currency       money.Currency `json:"currency"`
 }
 
 // OrderStatusUpdatedEvent order status update event
@@ -15,3 +27,74 @@ type OrderStatusUpdatedEvent struct {
 	Status  string `json:"status"`
 	Reason  string `json:"reason,omitempty"`
 }
+
+// OrderCancelRequestedEvent is published when a customer cancels an order.
+// Payment service refunds the debited amount, if any.
+type OrderCancelRequestedEvent struct {
+	OrderID uuid.UUID `json:"order_id"`
+	UserID  string    `json:"user_id"`
+	Reason  string    `json:"reason"`
+}
+
+// OrderReturnApprovedEvent is published when a return is approved. Payment
+// service refunds Amount once per ReturnID.
+type OrderReturnApprovedEvent struct {
+	ReturnID uuid.UUID         `json:"return_id"`
+	OrderID  uuid.UUID         `json:"order_id"`
+	UserID   string            `json:"user_id"`
+	Amount   money.Amount      `json:"amount"`
+	Currency money.Currency    `json:"currency"`
+	Items    []ReturnItemEvent `json:"items"`
+}
+
+// ReturnItemEvent is a returned line of an OrderReturnApprovedEvent.
+type ReturnItemEvent struct {
+	OrderItemID int64        `json:"item_id"`
+	ProductID   int64        `json:"product_id"`
+	Quantity    int          `json:"quantity"`
+	Amount      money.Amount `json:"amount"`
+}
+
+// OrderReturnRefundedEvent is published by payment service once the refund
+// of a return is credited to the account.
+type OrderReturnRefundedEvent struct {
+	ReturnID uuid.UUID      `json:"return_id"`
+	OrderID  uuid.UUID      `json:"order_id"`
+	Amount   money.Amount   `json:"amount"`
+	Currency money.Currency `json:"currency"`
+}
+
+// UnmarshalJSON rounds the amount to a minor unit: events published before
+// amounts were exact carry float64 values such as 199.98000000000002.
+func (e *OrderReturnRefundedEvent) UnmarshalJSON(data []byte) error {
+	type event OrderReturnRefundedEvent
+	wire := struct {
+		*event
+		Amount *money.LegacyAmount `json:"amount"`
+	}{(*event)(e), (*money.LegacyAmount)(&e.Amount)}
+	return json.Unmarshal(data, &wire)
+}
+
+// OrderStatusChangedEvent is published with every order status change. It
+// drives the order.paid and order.cancelled webhooks.
+type OrderStatusChangedEvent struct {
+	OrderID    uuid.UUID    `json:"order_id"`
+	UserID     string       `json:"user_id"`
+	FromStatus OrderStatus  `json:"from_status"`
+	ToStatus   OrderStatus  `json:"to_status"`
+	Reason     string       `json:"reason,omitempty"`
+	Source     StatusSource `json:"source"`
+}
+
+// ShipmentStatusChangedEvent is published when a shipment is packed and on
+// every later change of its status. FromStatus is empty for a new shipment.
+type ShipmentStatusChangedEvent struct {
+	ShipmentID     uuid.UUID      `json:"shipment_id"`
+	OrderID        uuid.UUID      `json:"order_id"`
+	UserID         string         `json:"user_id"`
+	FromStatus     ShipmentStatus `json:"from_status,omitempty"`
+	ToStatus       ShipmentStatus `json:"to_status"`
+	Carrier        string         `json:"carrier,omitempty"`
+	TrackingNumber string         `json:"tracking_number,omitempty"`
+	Items          []ShipmentItem `json:"items"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxIdempotencyKeyLength matches the idempotency_keys.key column.
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyRecord is a stored Idempotency-Key with the fingerprint of the
+// request that first used it. StatusCode is nil while that request is
+// still being processed.
+type IdempotencyRecord struct {
+	Key          string    `db:"key"`
+	Fingerprint  string    `db:"fingerprint"`
+	StatusCode   *int      `db:"status_code"`
+	ResponseBody []byte    `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// Completed reports whether the response of the original request has been stored.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != nil
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StockShortage describes a product that does not have enough available stock.
+type StockShortage struct {
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+// InsufficientStockError is returned when an order asks for more than is available.
+type InsufficientStockError struct {
+	Shortages []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, len(e.Shortages))
+	for i, s := range e.Shortages {
+		parts[i] = fmt.Sprintf("%s (requested %d, available %d)", s.Name, s.Requested, s.Available)
+	}
+	return "insufficient stock: " + strings.Join(parts, ", ")
+}
+
+// StockMovement is the effect of an order status transition on product stock.
+type StockMovement int
+
+const (
+	StockUnchanged StockMovement = iota
+	// StockRelease returns reserved quantities to available stock.
+	StockRelease
+	// StockCommit takes reserved quantities out of stock for good.
+	StockCommit
+	// StockRestock puts committed quantities back into stock.
+	StockRestock
+)
+
+// StockMovementFor returns how stock reserved for an order changes when the
+// order moves from one status to another. Stock is reserved on creation.
+func StockMovementFor(from, to OrderStatus) StockMovement {
+	switch {
+	case from == StatusNew && to == StatusCancelled:
+		return StockRelease
+	case from == StatusNew && to == StatusFinished:
+		return StockCommit
+	case from == StatusFinished && to == StatusCancelled:
+		return StockRestock
+	default:
+		return StockUnchanged
+	}
+}
@@ -1,9 +1,11 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type OrderStatus string
@@ -14,23 +16,43 @@ const (
 	StatusCancelled OrderStatus = "CANCELLED"
 )
 
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderNotCancellable = errors.New("order cannot be cancelled")
+)
+
 type Order struct {
-	ID          uuid.UUID   `json:"id" db:"id"`
-	UserID      string      `json:"user_id" db:"user_id"`
-	Items       []OrderItem `json:"items"`
-	TotalAmount float64     `json:"total_amount" db:"total_amount"`
-	Description string      `json:"description" db:"description"`
-	Status      OrderStatus `json:"status" db:"status"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID       `json:"id" db:"id"`
+	UserID          string          `json:"user_id" db:"user_id"`
+	Items           []OrderItem     `json:"items"`
+	SubtotalAmount  money.Amount    `json:"subtotal_amount" db:"subtotal_amount"` // Sum of the items before discounts
+	DiscountAmount  money.Amount    `json:"discount_amount" db:"discount_amount"`
+	Discounts       []OrderDiscount `json:"discounts"`
+	TaxAmount       money.Amount    `json:"tax_amount" db:"tax_amount"`
+	TaxInclusive    bool            `json:"tax_inclusive" db:"tax_inclusive"` // Item prices already include the tax
+	TotalAmount     money.Amount    `json:"total_amount" db:"total_amount"`
+	Currency        money.Currency  `json:"currency" db:"currency"`
+	Description     string          `json:"description" db:"description"`
+	ShippingAddress *Address        `json:"shipping_address,omitempty"` // Nil for orders placed without one
+	Status          OrderStatus     `json:"status" db:"status"`
+	CancelReason    string          `json:"cancel_reason,omitempty" db:"cancel_reason"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// MaxOrderItemQuantity bounds the quantity of a single order line, the same
+// way MaxCartItemQuantity bounds a cart line.
+const MaxOrderItemQuantity = MaxCartItemQuantity
+
 type OrderItem struct {
-	ID        int64     `json:"id" db:"id"`
-	OrderID   uuid.UUID `json:"order_id" db:"order_id"`
-	ProductID int64     `json:"product_id" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	Price     float64   `json:"price" db:"price"` // Price at the time of order
+	ID          int64        `json:"id" db:"id"`
+	OrderID     uuid.UUID    `json:"order_id" db:"order_id"`
+	ProductID   int64        `json:"product_id" db:"product_id"`
+	ProductName string       `json:"product_name" db:"product_name"` // Name at the time of order
+	Quantity    int          `json:"quantity" db:"quantity"`
+	Price       money.Amount `json:"price" db:"price"` // Price at the time of order
+	TaxRate     string       `json:"tax_rate" db:"tax_rate"`
+	TaxAmount   money.Amount `json:"tax_amount" db:"tax_amount"` // Tax on the whole line after discounts
 }
 
 type OrderRepository interface {
@@ -42,7 +64,7 @@ type OrderRepository interface {
 }
 
 type OrderService interface {
-	CreateOrder(userID string, amount float64, description string) (*Order, error)
+	CreateOrder(userID string, amount money.Amount, description string) (*Order, error)
 	GetOrder(id uuid.UUID) (*Order, error)
 	GetUserOrders(userID string) ([]*Order, error)
 	GetAllOrders() ([]*Order, error)
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+const (
+	DefaultOrderPageSize = 50
+	MaxOrderPageSize     = 200
+)
+
+// OrderSort defines the ordering of an order listing.
+type OrderSort string
+
+const (
+	SortCreatedAtDesc   OrderSort = "-created_at"
+	SortCreatedAtAsc    OrderSort = "created_at"
+	SortTotalAmountDesc OrderSort = "-total_amount"
+	SortTotalAmountAsc  OrderSort = "total_amount"
+)
+
+// ByAmount reports whether the listing is ordered by total amount.
+func (s OrderSort) ByAmount() bool {
+	return s == SortTotalAmountDesc || s == SortTotalAmountAsc
+}
+
+// Descending reports whether the listing is in descending order.
+func (s OrderSort) Descending() bool {
+	return s == "" || strings.HasPrefix(string(s), "-")
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// amountCursorPrefix marks cursors of listings ordered by total amount.
+const amountCursorPrefix = "amount:"
+
+// OrderCursor is a keyset position in an order listing: (created_at, id), or
+// (total_amount, id) when TotalAmount is set.
+type OrderCursor struct {
+	CreatedAt   time.Time
+	TotalAmount *money.Amount
+	ID          uuid.UUID
+}
+
+// NewOrderCursor returns the position of the order in a listing with the given sort.
+func NewOrderCursor(order *Order, sort OrderSort) OrderCursor {
+	if sort.ByAmount() {
+		amount := order.TotalAmount
+		return OrderCursor{TotalAmount: &amount, ID: order.ID}
+	}
+	return OrderCursor{CreatedAt: order.CreatedAt, ID: order.ID}
+}
+
+// Encode returns an opaque string representation of the cursor.
+func (c OrderCursor) Encode() string {
+	key := c.CreatedAt.UTC().Format(time.RFC3339Nano)
+	if c.TotalAmount != nil {
+		key = amountCursorPrefix + strconv.FormatInt(c.TotalAmount.Minor(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(key + "|" + c.ID.String()))
+}
+
+// DecodeOrderCursor parses a cursor produced by OrderCursor.Encode.
+func DecodeOrderCursor(s string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if minor, ok := strings.CutPrefix(parts[0], amountCursorPrefix); ok {
+		n, err := strconv.ParseInt(minor, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		amount := money.FromMinor(n)
+		return &OrderCursor{TotalAmount: &amount, ID: id}, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &OrderCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// OrderFilter describes a single page request of an order listing.
+// Nil and empty fields are not applied.
+type OrderFilter struct {
+	UserID      string
+	Statuses    []OrderStatus
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Currency is required with MinAmount and MaxAmount: amounts in
+	// different currencies are not comparable.
+	Currency  money.Currency
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	Sort      OrderSort
+	After     *OrderCursor
+	Limit     int
+}
+
+// OrderPage is a page of an order listing.
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
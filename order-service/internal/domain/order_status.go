@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrStatusWindowExpired     = errors.New("order status change window has expired")
+)
+
+// StatusSource identifies who initiated an order status change.
+type StatusSource string
+
+const (
+	SourceCustomer StatusSource = "customer"
+	SourcePayment  StatusSource = "payment"
+	SourceSystem   StatusSource = "system"
+)
+
+// orderTransitions lists every allowed status transition together with the
+// sources allowed to make it. Anything not listed here is rejected.
+var orderTransitions = map[OrderStatus]map[OrderStatus][]StatusSource{
+	StatusNew: {
+		StatusFinished:  {SourcePayment},
+		StatusCancelled: {SourcePayment, SourceCustomer, SourceSystem},
+	},
+	StatusFinished: {
+		// Only the customer may cancel a paid order, within the grace period.
+		StatusCancelled: {SourceCustomer},
+	},
+}
+
+// CanTransitionTo reports whether source may move an order from s to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus, source StatusSource) bool {
+	for _, allowed := range orderTransitions[s][next] {
+		if allowed == source {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange is a request to move an order to another status.
+type StatusChange struct {
+	OrderID uuid.UUID
+	To      OrderStatus
+	Reason  string
+	Source  StatusSource
+	// MaxStatusAge, if set, rejects the change when the order has been
+	// in its current status for longer than that.
+	MaxStatusAge time.Duration
+	// RejectIfFulfilling, if set, rejects the change with
+	// ErrOrderNotCancellable when the order has returns or shipments.
+	RejectIfFulfilling bool
+}
+
+// StatusHistoryEntry is a single recorded order status change.
+// FromStatus is nil for the entry recorded on order creation.
+type StatusHistoryEntry struct {
+	ID         int64        `json:"id" db:"id"`
+	OrderID    uuid.UUID    `json:"order_id" db:"order_id"`
+	FromStatus *OrderStatus `json:"from_status" db:"from_status"`
+	ToStatus   OrderStatus  `json:"to_status" db:"to_status"`
+	Reason     string       `json:"reason" db:"reason"`
+	Source     StatusSource `json:"source" db:"source"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
@@ -1,12 +1,51 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+var (
+	ErrProductNotFound    = errors.New("product not found")
+	ErrStockBelowReserved = errors.New("stock quantity cannot be less than the reserved quantity")
+)
 
 type Product struct {
-	ID          int64     `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Price       float64   `json:"price" db:"price"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int64          `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Description string         `json:"description" db:"description"`
+	Price       money.Amount   `json:"price" db:"price"`
+	Currency    money.Currency `json:"currency" db:"currency"`
+	CategoryID  *int64         `json:"category_id" db:"category_id"`
+	// StockQuantity is the quantity on hand, ReservedQuantity the part of it held by unpaid orders.
+	StockQuantity    int       `json:"stock_quantity" db:"stock_quantity"`
+	ReservedQuantity int       `json:"reserved_quantity" db:"reserved_quantity"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+	// DeletedAt is set for products removed from the catalog. They stay in
+	// the table so that historical order items keep referencing them.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// ProductUpdate lists the product fields to change; nil fields keep their
+// current values.
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Price       *money.Amount
+	Currency    *money.Currency
+	CategoryID  *int64
+	// ClearCategory removes the product from its category when CategoryID is nil.
+	ClearCategory bool
+	// StockQuantity sets the stock to a counted value; StockAdjustment adds
+	// to the current stock, which is safe against concurrent sales.
+	StockQuantity   *int
+	StockAdjustment *int
+}
+
+// AvailableQuantity returns the quantity that can still be ordered.
+func (p *Product) AvailableQuantity() int {
+	return p.StockQuantity - p.ReservedQuantity
 }
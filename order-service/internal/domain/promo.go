@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+var (
+	ErrPromoCodeNotFound = errors.New("promo code not found")
+	// ErrPromoCodeExhausted is returned when a usage limit is reached
+	// between validating the code and creating the order.
+	ErrPromoCodeExhausted = errors.New("promo code usage limit reached")
+)
+
+// DiscountType defines how a promo code reduces the order total.
+type DiscountType string
+
+const (
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+)
+
+// PromoCode is a discount code. Nil limits and bounds are not applied.
+// ProductIDs and CategoryIDs restrict the discount to matching order lines;
+// a category includes its subcategories.
+type PromoCode struct {
+	ID          int64         `json:"id" db:"id"`
+	Code        string        `json:"code" db:"code"`
+	Description string        `json:"description" db:"description"`
+	Type        DiscountType  `json:"discount_type" db:"discount_type"`
+	PercentOff  *int          `json:"percent_off,omitempty" db:"percent_off"`
+	AmountOff   *money.Amount `json:"amount_off,omitempty" db:"amount_off"`
+	// Currency of AmountOff and MinOrderAmount; the code only applies to orders in it.
+	Currency              *money.Currency `json:"currency,omitempty" db:"currency"`
+	MinOrderAmount        *money.Amount   `json:"min_order_amount,omitempty" db:"min_order_amount"`
+	MaxRedemptions        *int            `json:"max_redemptions,omitempty" db:"max_redemptions"`
+	MaxRedemptionsPerUser *int            `json:"max_redemptions_per_user,omitempty" db:"max_redemptions_per_user"`
+	RedemptionsCount      int             `json:"redemptions_count" db:"redemptions_count"`
+	StartsAt              *time.Time      `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt                *time.Time      `json:"ends_at,omitempty" db:"ends_at"`
+	Active                bool            `json:"active" db:"active"`
+	ProductIDs            []int64         `json:"product_ids"`
+	CategoryIDs           []int64         `json:"category_ids"`
+	CreatedAt             time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Restricted reports whether the code applies only to some products.
+func (p *PromoCode) Restricted() bool {
+	return len(p.ProductIDs) > 0 || len(p.CategoryIDs) > 0
+}
+
+// OrderDiscount is a discount line applied to an order.
+type OrderDiscount struct {
+	ID          int64        `json:"id" db:"id"`
+	OrderID     uuid.UUID    `json:"order_id" db:"order_id"`
+	PromoCodeID *int64       `json:"promo_code_id,omitempty" db:"promo_code_id"`
+	Code        string       `json:"code" db:"code"`
+	Description string       `json:"description" db:"description"`
+	Amount      money.Amount `json:"amount" db:"amount"`
+}
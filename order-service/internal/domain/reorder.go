@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+var ErrNothingToReorder = errors.New("none of the ordered products can be ordered again")
+
+// Reorder is a new order placed with the items of an earlier one, together
+// with how the items changed since.
+type Reorder struct {
+	OriginalOrderID uuid.UUID     `json:"original_order_id"`
+	Order           *Order        `json:"order"`
+	Items           []ReorderItem `json:"items"`
+	// PriceDifference is the subtotal of the new order less what the same
+	// quantities cost in the original order. Positive means prices went up.
+	PriceDifference money.Amount `json:"price_difference"`
+}
+
+// ReorderItem compares a product of the original order with its line in the
+// new order. Quantity is zero if the product was skipped.
+type ReorderItem struct {
+	ProductID        int64        `json:"product_id"`
+	ProductName      string       `json:"product_name"`
+	OriginalQuantity int          `json:"original_quantity"`
+	Quantity         int          `json:"quantity"`
+	OriginalPrice    money.Amount `json:"original_price"`
+	CurrentPrice     money.Amount `json:"current_price"`    // Zero if the product is no longer sold
+	PriceDifference  money.Amount `json:"price_difference"` // Per unit
+	// Problem explains why the product was skipped or its quantity reduced.
+	Problem string `json:"problem,omitempty"`
+}
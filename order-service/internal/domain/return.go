@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "REQUESTED"
+	ReturnApproved  ReturnStatus = "APPROVED"
+	ReturnRefunded  ReturnStatus = "REFUNDED"
+)
+
+var (
+	ErrReturnNotFound          = errors.New("return not found")
+	ErrOrderNotReturnable      = errors.New("order cannot be returned")
+	ErrInvalidReturnTransition = errors.New("invalid return status transition")
+)
+
+// returnTransitions lists every allowed return status transition.
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved},
+	// The refund is confirmed by payment service.
+	ReturnApproved: {ReturnRefunded},
+}
+
+// CanTransitionTo reports whether a return may move from s to next.
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderReturn is a return of some items of a FINISHED order. RefundAmount
+// is in the order currency and accounts for the order discounts.
+type OrderReturn struct {
+	ID           uuid.UUID      `json:"id" db:"id"`
+	OrderID      uuid.UUID      `json:"order_id" db:"order_id"`
+	UserID       string         `json:"user_id" db:"user_id"`
+	Status       ReturnStatus   `json:"status" db:"status"`
+	Reason       string         `json:"reason" db:"reason"`
+	Items        []ReturnItem   `json:"items"`
+	RefundAmount money.Amount   `json:"refund_amount" db:"refund_amount"`
+	Currency     money.Currency `json:"currency" db:"currency"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ReturnItem is a returned quantity of an order item.
+type ReturnItem struct {
+	ReturnID     uuid.UUID    `json:"-" db:"return_id"`
+	OrderItemID  int64        `json:"item_id" db:"order_item_id"`
+	ProductID    int64        `json:"product_id" db:"product_id"`
+	Quantity     int          `json:"quantity" db:"quantity"`
+	RefundAmount money.Amount `json:"refund_amount" db:"refund_amount"`
+}
+
+// PriceReturn checks the items of ret against what is left to return of the
+// order and computes the refund. returned holds the quantities of earlier
+// returns by order item ID, refunded the sum of their refunds.
+//
+// Every line is refunded at the price actually paid, i.e. less its share of
+// the order discounts and plus its share of the line tax if the tax was
+// charged on top of the prices. The return that takes back the last items refunds
+// whatever is left of the order total, so rounding never adds up to more
+// or less than was paid.
+func PriceReturn(order *Order, returned map[int64]int, refunded money.Amount, ret *OrderReturn) error {
+	items := make(map[int64]*OrderItem, len(order.Items))
+	remaining := 0
+	for i := range order.Items {
+		item := &order.Items[i]
+		items[item.ID] = item
+		remaining += item.Quantity - returned[item.ID]
+	}
+
+	verr := &ValidationError{}
+	if len(ret.Items) == 0 {
+		verr.Add("items", "return must contain at least one item")
+	}
+	seen := make(map[int64]bool, len(ret.Items))
+	for _, line := range ret.Items {
+		item := items[line.OrderItemID]
+		switch {
+		case item == nil:
+			verr.Add("items", fmt.Sprintf("item %d does not belong to the order", line.OrderItemID))
+		case seen[line.OrderItemID]:
+			verr.Add("items", fmt.Sprintf("item %d is listed more than once", line.OrderItemID))
+		case line.Quantity <= 0:
+			verr.Add("items", fmt.Sprintf("quantity of item %d must be positive", line.OrderItemID))
+		case line.Quantity > item.Quantity-returned[item.ID]:
+			verr.Add("items", fmt.Sprintf("only %d of item %d can be returned", item.Quantity-returned[item.ID], line.OrderItemID))
+		}
+		seen[line.OrderItemID] = true
+	}
+	if err := verr.OrNil(); err != nil {
+		return err
+	}
+
+	ret.RefundAmount = 0
+	ret.Currency = order.Currency
+	for i := range ret.Items {
+		line := &ret.Items[i]
+		item := items[line.OrderItemID]
+		lineTotal, err := item.Price.Mul(int64(line.Quantity))
+		if err != nil {
+			return err
+		}
+		line.ProductID = item.ProductID
+		line.RefundAmount = lineTotal
+		if order.DiscountAmount > 0 {
+			net := order.SubtotalAmount - order.DiscountAmount
+			if line.RefundAmount, err = net.Prorate(lineTotal, order.SubtotalAmount); err != nil {
+				return err
+			}
+		}
+		if !order.TaxInclusive && item.TaxAmount != 0 {
+			tax, err := item.TaxAmount.Prorate(money.FromMinor(int64(line.Quantity)), money.FromMinor(int64(item.Quantity)))
+			if err != nil {
+				return err
+			}
+			line.RefundAmount += tax
+		}
+		ret.RefundAmount += line.RefundAmount
+		remaining -= line.Quantity
+	}
+
+	if remaining == 0 {
+		last := &ret.Items[len(ret.Items)-1]
+		diff := order.TotalAmount - refunded - ret.RefundAmount
+		last.RefundAmount += diff
+		ret.RefundAmount += diff
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShipmentStatus string
+
+const (
+	ShipmentPacked    ShipmentStatus = "PACKED"
+	ShipmentShipped   ShipmentStatus = "SHIPPED"
+	ShipmentDelivered ShipmentStatus = "DELIVERED"
+	// ShipmentReturned means the carrier brought the parcel back undelivered.
+	ShipmentReturned ShipmentStatus = "RETURNED"
+)
+
+var (
+	ErrShipmentNotFound          = errors.New("shipment not found")
+	ErrOrderNotShippable         = errors.New("order cannot be shipped")
+	ErrInvalidShipmentTransition = errors.New("invalid shipment status transition")
+)
+
+// shipmentTransitions lists every allowed shipment status transition.
+var shipmentTransitions = map[ShipmentStatus][]ShipmentStatus{
+	ShipmentPacked:  {ShipmentShipped},
+	ShipmentShipped: {ShipmentDelivered, ShipmentReturned},
+}
+
+// CanTransitionTo reports whether a shipment may move from s to next.
+func (s ShipmentStatus) CanTransitionTo(next ShipmentStatus) bool {
+	for _, allowed := range shipmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Address is the shipping address of an order.
+type Address struct {
+	Recipient  string `json:"recipient" db:"recipient"`
+	Phone      string `json:"phone,omitempty" db:"phone"`
+	Line1      string `json:"line1" db:"line1"`
+	Line2      string `json:"line2,omitempty" db:"line2"`
+	City       string `json:"city" db:"city"`
+	Region     string `json:"region,omitempty" db:"region"`
+	PostalCode string `json:"postal_code" db:"postal_code"`
+	Country    string `json:"country" db:"country"` // ISO 3166-1 alpha-2
+}
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Normalize trims the fields and upper-cases the country code.
+func (a *Address) Normalize() {
+	for _, field := range []*string{&a.Recipient, &a.Phone, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode} {
+		*field = strings.TrimSpace(*field)
+	}
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Validate records the invalid fields of the address under prefix.
+func (a *Address) Validate(verr *ValidationError, prefix string) {
+	required := []struct {
+		name, value string
+	}{
+		{"recipient", a.Recipient},
+		{"line1", a.Line1},
+		{"city", a.City},
+		{"postal_code", a.PostalCode},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			verr.Add(prefix+"."+field.name, "is required")
+		}
+	}
+	if !countryCodePattern.MatchString(a.Country) {
+		verr.Add(prefix+".country", "must be a two-letter ISO 3166-1 code")
+	}
+}
+
+// Shipment is a parcel with some items of a FINISHED order. An order may be
+// shipped in several parcels.
+type Shipment struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	OrderID        uuid.UUID      `json:"order_id" db:"order_id"`
+	Status         ShipmentStatus `json:"status" db:"status"`
+	Carrier        string         `json:"carrier,omitempty" db:"carrier"`
+	TrackingNumber string         `json:"tracking_number,omitempty" db:"tracking_number"`
+	Items          []ShipmentItem `json:"items"`
+	ShippedAt      *time.Time     `json:"shipped_at,omitempty" db:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty" db:"delivered_at"`
+	ReturnedAt     *time.Time     `json:"returned_at,omitempty" db:"returned_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ShipmentItem is a quantity of an order item packed into a shipment.
+type ShipmentItem struct {
+	ShipmentID  uuid.UUID `json:"-" db:"shipment_id"`
+	OrderItemID int64     `json:"item_id" db:"order_item_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+}
+
+// ShipmentChange is a request to move a shipment to another status. Carrier
+// and TrackingNumber are recorded when the shipment is handed to the carrier.
+type ShipmentChange struct {
+	ShipmentID     uuid.UUID
+	To             ShipmentStatus
+	Carrier        string
+	TrackingNumber string
+}
+
+// PlanShipment checks the items of s against what is left to ship of the
+// order. shipped holds the quantities in earlier shipments by order item ID.
+// If s has no items, everything left to ship is packed into it.
+//
+// Items of a returned shipment still count as shipped: the warehouse packs
+// a new shipment for them explicitly if the order is to be sent again.
+func PlanShipment(order *Order, shipped map[int64]int, s *Shipment) error {
+	if len(s.Items) == 0 {
+		for _, item := range order.Items {
+			if left := item.Quantity - shipped[item.ID]; left > 0 {
+				s.Items = append(s.Items, ShipmentItem{OrderItemID: item.ID, Quantity: left})
+			}
+		}
+		if len(s.Items) == 0 {
+			return fmt.Errorf("%w: all items are already shipped", ErrOrderNotShippable)
+		}
+		return nil
+	}
+
+	items := make(map[int64]*OrderItem, len(order.Items))
+	for i := range order.Items {
+		items[order.Items[i].ID] = &order.Items[i]
+	}
+
+	verr := &ValidationError{}
+	seen := make(map[int64]bool, len(s.Items))
+	for _, si := range s.Items {
+		item, ok := items[si.OrderItemID]
+		switch {
+		case !ok:
+			verr.Add("items", fmt.Sprintf("item %d does not belong to the order", si.OrderItemID))
+		case seen[si.OrderItemID]:
+			verr.Add("items", fmt.Sprintf("item %d is listed more than once", si.OrderItemID))
+		case si.Quantity <= 0:
+			verr.Add("items", fmt.Sprintf("quantity of item %d must be positive", si.OrderItemID))
+		case si.Quantity > item.Quantity-shipped[item.ID]:
+			verr.Add("items", fmt.Sprintf("only %d of item %d left to ship", item.Quantity-shipped[item.ID], si.OrderItemID))
+		}
+		seen[si.OrderItemID] = true
+	}
+	return verr.OrNil()
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrTaxRateNotFound = errors.New("tax rate not found")
+
+// TaxRate is the tax rate of a destination country or region. CategoryID nil
+// means the rate applies to every category; Region "" covers the whole
+// country. Rate is a decimal fraction such as "0.2", kept as a string so as
+// not to lose precision.
+type TaxRate struct {
+	ID         int64     `json:"id" db:"id"`
+	CategoryID *int64    `json:"category_id" db:"category_id"`
+	Country    string    `json:"country" db:"country"`
+	Region     string    `json:"region" db:"region"`
+	Rate       string    `json:"rate" db:"rate"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// FindTaxRate picks the rate for a product sold to country and region out of
+// the rates of that country. categories is the product category followed by
+// its ancestors, nearest first.
+//
+// The most specific category wins: a rate for the product's own category
+// beats one for a parent category, which beats a rate for all categories.
+// Among rates for the same category, a rate for the region beats the
+// country-wide one. It returns nil if no rate applies.
+func FindTaxRate(rates []*TaxRate, categories []int64, country, region string) *TaxRate {
+	var (
+		best      *TaxRate
+		bestScore = -1
+	)
+	for _, rate := range rates {
+		if !strings.EqualFold(rate.Country, country) {
+			continue
+		}
+		regional := rate.Region != ""
+		if regional && !strings.EqualFold(rate.Region, region) {
+			continue
+		}
+
+		// Чем ближе категория ставки к категории товара, тем выше оценка
+		categoryScore := 0
+		if rate.CategoryID != nil {
+			depth := indexOf(categories, *rate.CategoryID)
+			if depth < 0 {
+				continue
+			}
+			categoryScore = len(categories) - depth
+		}
+		score := categoryScore * 2
+		if regional {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	return best
+}
+
+func indexOf(ids []int64, id int64) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
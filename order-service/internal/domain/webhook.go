@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventType is the type of an event delivered to webhook subscribers.
+type WebhookEventType string
+
+const (
+	WebhookOrderCreated   WebhookEventType = "order.created"
+	WebhookOrderPaid      WebhookEventType = "order.paid"
+	WebhookOrderCancelled WebhookEventType = "order.cancelled"
+)
+
+// WebhookEventTypes lists the event types a subscription may ask for.
+var WebhookEventTypes = []WebhookEventType{WebhookOrderCreated, WebhookOrderPaid, WebhookOrderCancelled}
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed means the delivery ran out of attempts.
+	DeliveryFailed = "failed"
+)
+
+var ErrWebhookSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// WebhookSubscription is a partner endpoint receiving order events.
+// Secret is returned only when the subscription is created.
+type WebhookSubscription struct {
+	ID                  uuid.UUID          `json:"id" db:"id"`
+	URL                 string             `json:"url" db:"url"`
+	EventTypes          []WebhookEventType `json:"event_types"`
+	Description         string             `json:"description" db:"description"`
+	Secret              string             `json:"secret,omitempty" db:"secret"`
+	Active              bool               `json:"active" db:"active"`
+	ConsecutiveFailures int                `json:"consecutive_failures" db:"consecutive_failures"`
+	DisabledAt          *time.Time         `json:"disabled_at,omitempty" db:"disabled_at"`
+	DisabledReason      string             `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	CreatedAt           time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is an event queued for one subscription.
+type WebhookDelivery struct {
+	ID             uuid.UUID        `json:"id" db:"id"`
+	SubscriptionID uuid.UUID        `json:"subscription_id" db:"subscription_id"`
+	EventID        uuid.UUID        `json:"event_id" db:"event_id"`
+	EventType      WebhookEventType `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage  `json:"payload" db:"payload"`
+	Status         string           `json:"status" db:"status"`
+	Attempts       int              `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at" db:"next_attempt_at"`
+	LastAttemptAt  *time.Time       `json:"last_attempt_at,omitempty" db:"last_attempt_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	AttemptLog     []WebhookAttempt `json:"attempt_log,omitempty"`
+	// URL and Secret of the subscription, filled in for the dispatcher.
+	URL    string `json:"-" db:"url"`
+	Secret string `json:"-" db:"secret"`
+}
+
+// WebhookAttempt is a recorded delivery attempt. StatusCode is nil when no
+// response was received.
+type WebhookAttempt struct {
+	ID         int64     `json:"id" db:"id"`
+	DeliveryID uuid.UUID `json:"delivery_id" db:"delivery_id"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode *int      `json:"status_code,omitempty" db:"status_code"`
+	Error      string    `json:"error,omitempty" db:"error"`
+	DurationMS int       `json:"duration_ms" db:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Succeeded reports whether the endpoint accepted the delivery with a 2xx response.
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.StatusCode != nil && *a.StatusCode >= 200 && *a.StatusCode < 300
+}
+
+// WebhookEventFor maps an outbox message to the webhook event it triggers, if any.
+func WebhookEventFor(messageType string, payload []byte) (WebhookEventType, bool) {
+	switch messageType {
+	case "order_created":
+		return WebhookOrderCreated, true
+	case "order_status_changed":
+		var event OrderStatusChangedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return "", false
+		}
+		switch event.ToStatus {
+		case StatusFinished:
+			return WebhookOrderPaid, true
+		case StatusCancelled:
+			return WebhookOrderCancelled, true
+		}
+	}
+	return "", false
+}
@@ -55,6 +55,36 @@ func (c *Consumer) ReadOrderStatusUpdatedEvent(ctx context.Context) (*domain.Ord
 	return &event, nil
 }
 
+// PaymentEvent is a message of the payments topic together with its type and
+// the correlation ID of the request that caused it, if any.
+type PaymentEvent struct {
+	Type          string
+	CorrelationID string
+	Payload       []byte
+}
+
+// ReadPaymentEvent reads the next message of the payments topic. The type is
+// taken from the "type" header; messages without it are order status updates.
+func (c *Consumer) ReadPaymentEvent(ctx context.Context) (*PaymentEvent, error) {
+	msg, err := c.reader.ReadMessage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	event := &PaymentEvent{Type: "order_status_updated", Payload: msg.Value}
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderType:
+			event.Type = string(h.Value)
+		case HeaderCorrelationID:
+			event.CorrelationID = string(h.Value)
+		}
+	}
+
+	log.Printf("Payment event received: Type=%s, Payload=%s", event.Type, string(msg.Value))
+	return event, nil
+}
+
 func (c *Consumer) Close() error {
 	return c.reader.Close()
 }
@@ -5,33 +5,84 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 	"github.com/segmentio/kafka-go"
 )
 
 const (
 	OrderCreatedTopic = "orders"
+	// Source is the value of the source header of every record.
+	Source = "order-service"
 )
 
+// Заголовки, которые получает каждая запись, опубликованная из outbox
+const (
+	HeaderMessageID     = "message_id"
+	HeaderType          = "type"
+	HeaderSchemaVersion = "schema_version"
+	HeaderSource        = "source"
+	HeaderTimestamp     = "timestamp"
+	HeaderCorrelationID = "correlation_id"
+	HeaderAggregateType = "aggregate_type"
+	HeaderAggregateID   = "aggregate_id"
+)
+
+// Route tells where outbox messages of one type are published and which
+// version of their payload schema they carry.
+type Route struct {
+	Topic         string
+	SchemaVersion int
+}
+
+// Routes maps outbox message types to topics. Payment service consumes the
+// orders topic; the other topics are for downstream consumers that only care
+// about status changes. A new event type only needs an entry here.
+var Routes = map[string]Route{
+	"order_created":           {Topic: OrderCreatedTopic, SchemaVersion: 1},
+	"order_cancel_requested":  {Topic: OrderCreatedTopic, SchemaVersion: 1},
+	"order_return_approved":   {Topic: OrderCreatedTopic, SchemaVersion: 1},
+	"order_status_changed":    {Topic: "order-status", SchemaVersion: 1},
+	"shipment_status_changed": {Topic: "shipments", SchemaVersion: 1},
+}
+
+// DefaultRoute is used for message types missing from Routes.
+var DefaultRoute = Route{Topic: OrderCreatedTopic, SchemaVersion: 1}
+
 type Producer struct {
 	writer  *kafka.Writer
 	brokers []string
+	routes  map[string]Route
 }
 
 func NewProducer(brokers []string) *Producer {
-	// A writer is created for each topic. For now, we only have one.
-	writer := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  brokers,
-		Topic:    OrderCreatedTopic,
-		Balancer: &kafka.LeastBytes{},
-	})
+	// Топик задаётся в каждой записи, поэтому один writer обслуживает все топики.
+	// Партиция выбирается по хешу ключа, чтобы события заказа шли по порядку
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+		// Сообщения пишутся по одному и синхронно; по умолчанию writer ждёт
+		// заполнения пачки до секунды, что задерживало бы каждую публикацию
+		BatchTimeout: 5 * time.Millisecond,
+	}
 
 	return &Producer{
 		writer:  writer,
 		brokers: brokers,
+		routes:  Routes,
+	}
+}
+
+// Route returns the route of the message type.
+func (p *Producer) Route(messageType string) Route {
+	if route, ok := p.routes[messageType]; ok {
+		return route
 	}
+	return DefaultRoute
 }
 
 func (p *Producer) PublishOrderCreated(ctx context.Context, event domain.OrderCreatedEvent) error {
@@ -41,6 +92,7 @@ func (p *Producer) PublishOrderCreated(ctx context.Context, event domain.OrderCr
 	}
 
 	msg := kafka.Message{
+		Topic: OrderCreatedTopic,
 		Key:   []byte(event.OrderID.String()),
 		Value: valueBytes,
 	}
@@ -86,15 +138,40 @@ func (p *Producer) Close() error {
 	return p.writer.Close()
 }
 
-// Publish реализует интерфейс OutboxPublisher
-func (p *Producer) Publish(ctx context.Context, message *outbox.OutboxMessage) error {
+// Publish реализует интерфейс outbox.Publisher. Топик выбирается по типу
+// сообщения, заголовки позволяют потребителям различать события, отбрасывать
+// дубликаты по message_id и связывать их с исходным запросом.
+func (p *Producer) Publish(ctx context.Context, message *outbox.Message) error {
+	route := p.Route(message.Type)
+	// Сообщение, не вызванное запросом, начинает собственную цепочку
+	correlationID := message.CorrelationID
+	if correlationID == "" {
+		correlationID = message.ID.String()
+	}
+	// События одного агрегата получают один ключ и попадают в одну партицию
+	key := message.AggregateID
+	if key == "" {
+		key = message.ID.String()
+	}
 	msg := kafka.Message{
-		Key:   message.ID[:],
+		Topic: route.Topic,
+		Key:   []byte(key),
 		Value: message.Payload,
+		Time:  message.CreatedAt,
+		Headers: []kafka.Header{
+			{Key: HeaderMessageID, Value: []byte(message.ID.String())},
+			{Key: HeaderType, Value: []byte(message.Type)},
+			{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(route.SchemaVersion))},
+			{Key: HeaderSource, Value: []byte(Source)},
+			{Key: HeaderTimestamp, Value: []byte(message.CreatedAt.UTC().Format(time.RFC3339Nano))},
+			{Key: HeaderCorrelationID, Value: []byte(correlationID)},
+			{Key: HeaderAggregateType, Value: []byte(message.AggregateType)},
+			{Key: HeaderAggregateID, Value: []byte(message.AggregateID)},
+		},
 	}
 	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		return fmt.Errorf("failed to write message to topic %s: %w", OrderCreatedTopic, err)
+		return fmt.Errorf("failed to write message to topic %s: %w", route.Topic, err)
 	}
-	log.Printf("Outbox message sent to Kafka: %s", string(message.Payload))
+	log.Printf("Outbox message %s sent to Kafka topic %s: %s", message.Type, route.Topic, string(message.Payload))
 	return nil
 }
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+type CartRepository struct {
+	db *sqlx.DB
+}
+
+func NewCartRepository(db *sqlx.DB) *CartRepository {
+	return &CartRepository{db: db}
+}
+
+// GetItems returns the user's cart lines with the current name, price and
+// available stock of each product.
+func (r *CartRepository) GetItems(ctx context.Context, userID string) ([]*domain.CartItem, error) {
+	query := `
+		SELECT ci.user_id, ci.product_id, ci.quantity, ci.updated_at,
+			p.name AS product_name, p.price, p.currency,
+			p.stock_quantity - p.reserved_quantity AS available_quantity,
+			p.deleted_at IS NOT NULL AS product_deleted
+		FROM cart_items ci
+		JOIN products p ON p.id = ci.product_id
+		WHERE ci.user_id = $1
+		ORDER BY ci.created_at, ci.product_id
+	`
+	var items []*domain.CartItem
+	if err := r.db.SelectContext(ctx, &items, query, userID); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SetItem sets the quantity of a product in the cart, adding the line if needed.
+func (r *CartRepository) SetItem(ctx context.Context, userID string, productID int64, quantity int) error {
+	query := `
+		INSERT INTO cart_items (user_id, product_id, quantity)
+		SELECT $1, id, $3 FROM products WHERE id = $2 AND deleted_at IS NULL
+		ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, productID, quantity)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrProductNotFound
+	}
+	return nil
+}
+
+func (r *CartRepository) RemoveItem(ctx context.Context, userID string, productID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	return err
+}
+
+func (r *CartRepository) Clear(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM cart_items WHERE user_id = $1`, userID)
+	return err
+}
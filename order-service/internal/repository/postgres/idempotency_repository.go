@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const idempotencyColumns = "key, fingerprint, status_code, response_body, created_at, expires_at"
+
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims the key for a new request. If the key is already taken by
+// an unexpired record, that record is returned with created set to false.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*domain.IdempotencyRecord, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// An expired key is free to be reused
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND expires_at <= NOW()`, key); err != nil {
+		return nil, false, err
+	}
+
+	record := &domain.IdempotencyRecord{}
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + $3 * INTERVAL '1 second')
+		ON CONFLICT (key) DO NOTHING
+		RETURNING `+idempotencyColumns,
+		key, fingerprint, ttl.Seconds(),
+	).StructScan(record)
+	created := err == nil
+	if err == sql.ErrNoRows {
+		err = tx.GetContext(ctx, record, `SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE key = $1`, key)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return record, created, tx.Commit()
+}
+
+// Complete stores the response of the request that reserved the key.
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, statusCode int, body []byte) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE key = $3`,
+		statusCode, body, key,
+	)
+	return err
+}
+
+// Release frees a reserved key whose request failed, so that it can be retried.
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL`, key)
+	return err
+}
+
+// DeleteExpired removes expired keys and returns how many were deleted.
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// reserveStock reserves stock for the order items within the caller's transaction.
+// Products are locked in id order to avoid deadlocks between concurrent orders.
+// If any product is short, it returns *domain.InsufficientStockError naming all of them;
+// a product deleted since it was read is reported with nothing available.
+func reserveStock(ctx context.Context, tx *sqlx.Tx, items []domain.OrderItem) error {
+	quantities := make(map[int64]int)
+	for _, item := range items {
+		quantities[item.ProductID] += item.Quantity
+	}
+	productIDs := make([]int64, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+
+	reserveQuery := `
+		UPDATE products
+		SET reserved_quantity = reserved_quantity + $1
+		WHERE id = $2 AND deleted_at IS NULL AND stock_quantity - reserved_quantity >= $1
+	`
+	var shortages []domain.StockShortage
+	for _, id := range productIDs {
+		res, err := tx.ExecContext(ctx, reserveQuery, quantities[id], id)
+		if err != nil {
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected > 0 {
+			continue
+		}
+
+		shortage := domain.StockShortage{ProductID: id, Requested: quantities[id]}
+		err = tx.QueryRowxContext(ctx,
+			`SELECT name, CASE WHEN deleted_at IS NULL THEN stock_quantity - reserved_quantity ELSE 0 END FROM products WHERE id = $1`, id,
+		).Scan(&shortage.Name, &shortage.Available)
+		if err != nil {
+			return err
+		}
+		shortages = append(shortages, shortage)
+	}
+
+	if len(shortages) > 0 {
+		return &domain.InsufficientStockError{Shortages: shortages}
+	}
+	return nil
+}
+
+// moveStock applies the stock movement for all items of an order within the caller's transaction.
+func moveStock(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID, movement domain.StockMovement) error {
+	var query string
+	switch movement {
+	case domain.StockRelease:
+		query = `UPDATE products SET reserved_quantity = reserved_quantity - $1, updated_at = NOW() WHERE id = $2`
+	case domain.StockCommit:
+		query = `UPDATE products SET stock_quantity = stock_quantity - $1, reserved_quantity = reserved_quantity - $1, updated_at = NOW() WHERE id = $2`
+	case domain.StockRestock:
+		query = `UPDATE products SET stock_quantity = stock_quantity + $1, updated_at = NOW() WHERE id = $2`
+	default:
+		return nil
+	}
+
+	var items []struct {
+		ProductID int64 `db:"product_id"`
+		Quantity  int   `db:"quantity"`
+	}
+	err := tx.SelectContext(ctx, &items, `
+		SELECT product_id, SUM(quantity) AS quantity
+		FROM order_items
+		WHERE order_id = $1
+		GROUP BY product_id
+		ORDER BY product_id
+	`, orderID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if _, err := tx.ExecContext(ctx, query, item.Quantity, item.ProductID); err != nil {
+			return fmt.Errorf("failed to update stock of product %d: %w", item.ProductID, err)
+		}
+	}
+	return nil
+}
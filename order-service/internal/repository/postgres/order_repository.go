@@ -3,13 +3,23 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
+const orderColumns = "id, user_id, status, subtotal_amount, discount_amount, tax_amount, tax_inclusive, total_amount, currency, description, cancel_reason, created_at, updated_at"
+
+const orderItemColumns = "id, order_id, product_id, product_name, quantity, price, tax_rate, tax_amount"
+
+const shippingAddressColumns = "recipient, phone, line1, line2, city, region, postal_code, country"
+
 type OrderRepository struct {
 	db *sqlx.DB
 }
@@ -18,41 +28,8 @@ func NewOrderRepository(db *sqlx.DB) *OrderRepository {
 	return &OrderRepository{db: db}
 }
 
-func (r *OrderRepository) Create(ctx context.Context, order *domain.Order) error {
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback() // Rollback is ignored if tx is committed
-
-	orderQuery := `
-		INSERT INTO orders (id, user_id, status, total_amount, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-		RETURNING created_at, updated_at
-	`
-	err = tx.QueryRowxContext(ctx, orderQuery,
-		order.ID, order.UserID, order.Status, order.TotalAmount, order.Description,
-	).Scan(&order.CreatedAt, &order.UpdatedAt)
-	if err != nil {
-		return err
-	}
-
-	itemQuery := `
-		INSERT INTO order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
-	`
-	for _, item := range order.Items {
-		_, err := tx.ExecContext(ctx, itemQuery, order.ID, item.ProductID, item.Quantity, item.Price)
-		if err != nil {
-			return err
-		}
-	}
-
-	return tx.Commit()
-}
-
 func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
-	query := `SELECT id, user_id, status, total_amount, description, created_at, updated_at FROM orders WHERE id = $1`
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
 	order := &domain.Order{}
 	err := r.db.GetContext(ctx, order, query, id)
 	if err == sql.ErrNoRows {
@@ -62,84 +39,561 @@ func (r *OrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Or
 		return nil, err
 	}
 
-	// TODO: Fetch order items as well
+	if err := r.loadDetails(ctx, []*domain.Order{order}); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
 func (r *OrderRepository) GetByUserID(ctx context.Context, userID string) ([]*domain.Order, error) {
-	query := `SELECT id, user_id, status, total_amount, description, created_at, updated_at FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
 	var orders []*domain.Order
 	err := r.db.SelectContext(ctx, &orders, query, userID)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: Fetch order items for each order
+	if err := r.loadDetails(ctx, orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
 
 func (r *OrderRepository) GetAll(ctx context.Context) ([]*domain.Order, error) {
-	query := `SELECT id, user_id, status, total_amount, description, created_at, updated_at FROM orders ORDER BY created_at DESC`
+	query := `SELECT ` + orderColumns + ` FROM orders ORDER BY created_at DESC`
 	var orders []*domain.Order
 	err := r.db.SelectContext(ctx, &orders, query)
 	if err != nil {
 		return nil, err
 	}
-	// TODO: Fetch order items for each order
+	if err := r.loadDetails(ctx, orders); err != nil {
+		return nil, err
+	}
 	return orders, nil
 }
 
-func (r *OrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.OrderStatus) error {
-	query := `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, status, id)
-	return err
+// GetStale returns up to limit orders, without items, that have been in
+// status since before olderThan ago, oldest first. Age is measured by the
+// database clock so that replicas agree on it.
+func (r *OrderRepository) GetStale(ctx context.Context, status domain.OrderStatus, olderThan time.Duration, limit int) ([]*domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders
+		WHERE status = $1 AND updated_at < NOW() - make_interval(secs => $2)
+		ORDER BY updated_at
+		LIMIT $3
+	`
+	var orders []*domain.Order
+	if err := r.db.SelectContext(ctx, &orders, query, status, olderThan.Seconds(), limit); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// List returns up to filter.Limit orders matching the filter, positioned
+// after filter.After in (created_at, id) or (total_amount, id) keyset order.
+func (r *OrderRepository) List(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = "+arg(filter.UserID))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = arg(status)
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(statuses, ", ")+")")
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedTo))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "currency = "+arg(filter.Currency))
+	}
+	if filter.MinAmount != nil {
+		conditions = append(conditions, "total_amount >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		conditions = append(conditions, "total_amount <= "+arg(*filter.MaxAmount))
+	}
+
+	key := "created_at"
+	if filter.Sort.ByAmount() {
+		key = "total_amount"
+	}
+	direction, comparison := "DESC", "<"
+	if !filter.Sort.Descending() {
+		direction, comparison = "ASC", ">"
+	}
+	if after := filter.After; after != nil {
+		var position interface{} = after.CreatedAt
+		if after.TotalAmount != nil {
+			position = *after.TotalAmount
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)",
+			key, comparison, arg(position), arg(after.ID.String())))
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", key, direction, direction, arg(filter.Limit))
+
+	var orders []*domain.Order
+	if err := r.db.SelectContext(ctx, &orders, query, args...); err != nil {
+		return nil, err
+	}
+	if err := r.loadDetails(ctx, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// loadDetails attaches items and discount lines to the given orders.
+func (r *OrderRepository) loadDetails(ctx context.Context, orders []*domain.Order) error {
+	if err := r.loadItems(ctx, orders); err != nil {
+		return err
+	}
+	if err := r.loadDiscounts(ctx, orders); err != nil {
+		return err
+	}
+	return r.loadShippingAddresses(ctx, orders)
+}
+
+// loadItems fetches the items of all given orders with a single query
+// and attaches them to the corresponding orders.
+func (r *OrderRepository) loadItems(ctx context.Context, orders []*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(orders))
+	byID := make(map[uuid.UUID]*domain.Order, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		byID[order.ID] = order
+		order.Items = []domain.OrderItem{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT `+orderItemColumns+`
+		FROM order_items
+		WHERE order_id IN (?)
+		ORDER BY order_id, id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	query = r.db.Rebind(query)
+
+	var items []domain.OrderItem
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if order, ok := byID[item.OrderID]; ok {
+			order.Items = append(order.Items, item)
+		}
+	}
+	return nil
+}
+
+// loadDiscounts fetches the discount lines of all given orders with a single query.
+func (r *OrderRepository) loadDiscounts(ctx context.Context, orders []*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(orders))
+	byID := make(map[uuid.UUID]*domain.Order, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		byID[order.ID] = order
+		order.Discounts = []domain.OrderDiscount{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT id, order_id, promo_code_id, code, description, amount
+		FROM order_discounts
+		WHERE order_id IN (?)
+		ORDER BY order_id, id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	query = r.db.Rebind(query)
+
+	var discounts []domain.OrderDiscount
+	if err := r.db.SelectContext(ctx, &discounts, query, args...); err != nil {
+		return err
+	}
+
+	for _, discount := range discounts {
+		if order, ok := byID[discount.OrderID]; ok {
+			order.Discounts = append(order.Discounts, discount)
+		}
+	}
+	return nil
+}
+
+func (r *OrderRepository) loadShippingAddresses(ctx context.Context, orders []*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(orders))
+	byID := make(map[uuid.UUID]*domain.Order, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+		byID[order.ID] = order
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT order_id, `+shippingAddressColumns+`
+		FROM order_shipping_addresses
+		WHERE order_id IN (?)
+	`, ids)
+	if err != nil {
+		return err
+	}
+	query = r.db.Rebind(query)
+
+	var rows []struct {
+		OrderID uuid.UUID `db:"order_id"`
+		domain.Address
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if order, ok := byID[row.OrderID]; ok {
+			address := row.Address
+			order.ShippingAddress = &address
+		}
+	}
+	return nil
+}
+
+// ChangeStatus moves an order to change.To if the transition is allowed by the
+// order state machine, records it in the status history and saves the outbox
+// messages, all in one transaction. An order_status_changed message is always
+// added, so the outbox carries every change. The order row is locked for the
+// duration, so concurrent changes of the same order are applied one after another.
+func (r *OrderRepository) ChangeStatus(ctx context.Context, change domain.StatusChange, outboxMsgs ...*outbox.Message) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	var (
+		from       domain.OrderStatus
+		userID     string
+		ageSeconds float64
+	)
+	err = tx.QueryRowxContext(ctx,
+		`SELECT status, user_id, EXTRACT(EPOCH FROM NOW() - updated_at) FROM orders WHERE id = $1 FOR UPDATE`,
+		change.OrderID,
+	).Scan(&from, &userID, &ageSeconds)
+	if err == sql.ErrNoRows {
+		return domain.ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !from.CanTransitionTo(change.To, change.Source) {
+		return fmt.Errorf("%w: %s -> %s by %s", domain.ErrInvalidStatusTransition, from, change.To, change.Source)
+	}
+	if change.MaxStatusAge > 0 && ageSeconds > change.MaxStatusAge.Seconds() {
+		return fmt.Errorf("%w: order has been %s for %s", domain.ErrStatusWindowExpired, from,
+			(time.Duration(ageSeconds) * time.Second).String())
+	}
+	// Возвраты и отгрузки создаются под той же блокировкой заказа, поэтому
+	// проверка здесь не пропустит созданные параллельно
+	if change.RejectIfFulfilling {
+		var hasReturns, hasShipments bool
+		err = tx.QueryRowxContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM order_returns WHERE order_id = $1),
+			       EXISTS (SELECT 1 FROM shipments WHERE order_id = $1)`,
+			change.OrderID,
+		).Scan(&hasReturns, &hasShipments)
+		if err != nil {
+			return err
+		}
+		if hasReturns {
+			return fmt.Errorf("%w: order has returns", domain.ErrOrderNotCancellable)
+		}
+		if hasShipments {
+			return fmt.Errorf("%w: order has shipments", domain.ErrOrderNotCancellable)
+		}
+	}
+
+	query := `
+		UPDATE orders
+		SET status = $1,
+		    cancel_reason = CASE WHEN $2 THEN $3 ELSE cancel_reason END,
+		    updated_at = NOW()
+		WHERE id = $4
+	`
+	if err := moveStock(ctx, tx, change.OrderID, domain.StockMovementFor(from, change.To)); err != nil {
+		return err
+	}
+
+	// Отмена из любого статуса возвращает покупателю промокоды: неоплаченный
+	// заказ ничего не списал, оплаченный получает полный возврат
+	cancelled := change.To == domain.StatusCancelled
+	if cancelled {
+		if err := releasePromoCodesTx(ctx, tx, change.OrderID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, query, change.To, cancelled, change.Reason, change.OrderID); err != nil {
+		return err
+	}
+
+	entry := &domain.StatusHistoryEntry{
+		OrderID:    change.OrderID,
+		FromStatus: &from,
+		ToStatus:   change.To,
+		Reason:     change.Reason,
+		Source:     change.Source,
+	}
+	if err := insertStatusHistory(ctx, tx, userID, entry); err != nil {
+		return err
+	}
+
+	changedMsg, err := statusChangedMessage(change, userID, from)
+	if err != nil {
+		return err
+	}
+	for _, msg := range append(outboxMsgs, changedMsg) {
+		if err := insertOutboxMessage(ctx, tx, msg); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func statusChangedMessage(change domain.StatusChange, userID string, from domain.OrderStatus) (*outbox.Message, error) {
+	return newOutboxMessage("order_status_changed", change.OrderID, domain.OrderStatusChangedEvent{
+		OrderID:    change.OrderID,
+		UserID:     userID,
+		FromStatus: from,
+		ToStatus:   change.To,
+		Reason:     change.Reason,
+		Source:     change.Source,
+	})
+}
+
+// GetStatusHistory returns the status changes of an order, oldest first.
+func (r *OrderRepository) GetStatusHistory(ctx context.Context, orderID uuid.UUID) ([]*domain.StatusHistoryEntry, error) {
+	query := `
+		SELECT id, order_id, from_status, to_status, reason, source, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	history := []*domain.StatusHistoryEntry{}
+	if err := r.db.SelectContext(ctx, &history, query, orderID); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
-func (r *OrderRepository) CreateWithOutbox(ctx context.Context, order *domain.Order, outboxMsg *outbox.OutboxMessage) error {
+// GetUserStatusChanges returns up to limit status changes of the user's
+// orders recorded after the entry afterID, oldest first. The entries of one
+// user become visible in ID order (see insertStatusHistory), so a reader
+// that has seen afterID cannot miss an earlier entry committed later.
+func (r *OrderRepository) GetUserStatusChanges(ctx context.Context, userID string, afterID int64, limit int) ([]*domain.StatusHistoryEntry, error) {
+	query := `
+		SELECT h.id, h.order_id, h.from_status, h.to_status, h.reason, h.source, h.created_at
+		FROM order_status_history h
+		JOIN orders o ON o.id = h.order_id
+		WHERE o.user_id = $1 AND h.id > $2
+		ORDER BY h.id
+		LIMIT $3
+	`
+	changes := []*domain.StatusHistoryEntry{}
+	if err := r.db.SelectContext(ctx, &changes, query, userID, afterID, limit); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetLastUserStatusChangeID returns the ID of the latest status change of
+// the user's orders, or 0 if there is none.
+func (r *OrderRepository) GetLastUserStatusChangeID(ctx context.Context, userID string) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(h.id), 0)
+		FROM order_status_history h
+		JOIN orders o ON o.id = h.order_id
+		WHERE o.user_id = $1
+	`
+	var id int64
+	err := r.db.GetContext(ctx, &id, query, userID)
+	return id, err
+}
+
+// insertStatusHistory records a status change of one of the user's orders.
+// IDs come from a sequence and are taken before commit, so two concurrent
+// transactions could commit their entries out of ID order and a stream
+// reading past the higher ID would skip the lower one. The user's entries
+// are therefore written under a transaction-level advisory lock: the next
+// entry of the same user gets its ID only after the previous one commits.
+// The insert must stay the last statement that locks shared rows.
+func insertStatusHistory(ctx context.Context, tx *sqlx.Tx, userID string, entry *domain.StatusHistoryEntry) error {
+	_, err := tx.ExecContext(ctx,
+		`SELECT pg_advisory_xact_lock(hashtextextended('order_status_history:' || $1, 0))`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO order_status_history (order_id, from_status, to_status, reason, source, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+	return tx.QueryRowxContext(ctx, query,
+		entry.OrderID, entry.FromStatus, entry.ToStatus, entry.Reason, entry.Source,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (r *OrderRepository) CreateWithOutbox(ctx context.Context, order *domain.Order, outboxMsg *outbox.Message) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback() // Rollback is ignored if tx is committed
 
+	if err := createOrderTx(ctx, tx, order, outboxMsg); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OrderRepository) CreateFromCart(ctx context.Context, order *domain.Order, outboxMsg *outbox.Message) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Удаляем из корзины ровно те строки, из которых собран заказ. Если
+	// корзину изменили или уже оформили параллельно, строки не совпадут.
+	productIDs := make([]int64, len(order.Items))
+	quantities := make([]int64, len(order.Items))
+	for i, item := range order.Items {
+		productIDs[i] = item.ProductID
+		quantities[i] = int64(item.Quantity)
+	}
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM cart_items
+		WHERE user_id = $1
+			AND (product_id, quantity) IN (SELECT * FROM unnest($2::bigint[], $3::int[]))`,
+		order.UserID, pq.Array(productIDs), pq.Array(quantities),
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(order.Items)) {
+		return domain.ErrCartChanged
+	}
+
+	if err := createOrderTx(ctx, tx, order, outboxMsg); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// createOrderTx reserves stock and inserts the order, its items, the
+// redemptions of its promo codes, the initial status history entry and
+// the outbox message.
+func createOrderTx(ctx context.Context, tx *sqlx.Tx, order *domain.Order, outboxMsg *outbox.Message) error {
+	// Резервируем товары на складе до оплаты заказа
+	if err := reserveStock(ctx, tx, order.Items); err != nil {
+		return err
+	}
+
 	orderQuery := `
-		INSERT INTO orders (id, user_id, status, total_amount, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		INSERT INTO orders (id, user_id, status, subtotal_amount, discount_amount, tax_amount, tax_inclusive, total_amount, currency, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
 		RETURNING created_at, updated_at
 	`
-	err = tx.QueryRowxContext(ctx, orderQuery,
-		order.ID, order.UserID, order.Status, order.TotalAmount, order.Description,
+	err := tx.QueryRowxContext(ctx, orderQuery,
+		order.ID, order.UserID, order.Status, order.SubtotalAmount, order.DiscountAmount, order.TaxAmount, order.TaxInclusive,
+		order.TotalAmount, order.Currency.OrDefault(), order.Description,
 	).Scan(&order.CreatedAt, &order.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
 	itemQuery := `
-		INSERT INTO order_items (order_id, product_id, quantity, price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO order_items (order_id, product_id, product_name, quantity, price, tax_rate, tax_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
 	`
-	for _, item := range order.Items {
-		_, err := tx.ExecContext(ctx, itemQuery, order.ID, item.ProductID, item.Quantity, item.Price)
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		if item.TaxRate == "" {
+			item.TaxRate = "0"
+		}
+		err := tx.QueryRowxContext(ctx, itemQuery,
+			order.ID, item.ProductID, item.ProductName, item.Quantity, item.Price, item.TaxRate, item.TaxAmount,
+		).Scan(&item.ID)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Сохраняем outbox сообщение
-	outboxQuery := `
-		INSERT INTO outbox_messages (id, type, payload, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-	_, err = tx.ExecContext(ctx, outboxQuery,
-		outboxMsg.ID,
-		outboxMsg.Type,
-		outboxMsg.Payload,
-		outboxMsg.Status,
-		outboxMsg.CreatedAt,
-		outboxMsg.UpdatedAt,
-	)
-	if err != nil {
+	if address := order.ShippingAddress; address != nil {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_shipping_addresses (order_id, `+shippingAddressColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			order.ID, address.Recipient, address.Phone, address.Line1, address.Line2,
+			address.City, address.Region, address.PostalCode, address.Country,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Погашение промокодов фиксируется в той же транзакции, что и заказ
+	if err := redeemPromoCodesTx(ctx, tx, order); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	initial := &domain.StatusHistoryEntry{
+		OrderID:  order.ID,
+		ToStatus: order.Status,
+		Reason:   "Order created",
+		Source:   domain.SourceCustomer,
+	}
+	if err := insertStatusHistory(ctx, tx, order.UserID, initial); err != nil {
+		return err
+	}
+
+	// Сохраняем outbox сообщение
+	return insertOutboxMessage(ctx, tx, outboxMsg)
 }
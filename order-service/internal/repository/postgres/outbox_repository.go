@@ -3,31 +3,44 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
+// OutboxRepository saves outbox messages; claiming and finishing them for
+// the relay is shared through outbox.PostgresRepository.
 type OutboxRepository struct {
+	*outbox.PostgresRepository
 	db *sql.DB
 }
 
 func NewOutboxRepository(db *sql.DB) *OutboxRepository {
-	return &OutboxRepository{db: db}
+	return &OutboxRepository{PostgresRepository: outbox.NewPostgresRepository(db), db: db}
 }
 
-func (r *OutboxRepository) Save(ctx context.Context, message *outbox.OutboxMessage) error {
+func (r *OutboxRepository) Save(ctx context.Context, message *outbox.Message) error {
+	if message.CorrelationID == "" {
+		message.CorrelationID = domain.CorrelationID(ctx)
+	}
 	query := `
-		INSERT INTO outbox_messages (id, type, payload, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO outbox_messages (id, type, aggregate_type, aggregate_id, payload, status, correlation_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		message.ID,
 		message.Type,
+		message.AggregateType,
+		message.AggregateID,
 		message.Payload,
 		message.Status,
+		message.CorrelationID,
 		message.CreatedAt,
 		message.UpdatedAt,
 	)
@@ -35,48 +48,65 @@ func (r *OutboxRepository) Save(ctx context.Context, message *outbox.OutboxMessa
 	return err
 }
 
-func (r *OutboxRepository) GetPending(ctx context.Context, limit int) ([]*outbox.OutboxMessage, error) {
-	query := `
-		SELECT id, type, payload, status, created_at, updated_at
-		FROM outbox_messages
-		WHERE status = 'pending'
-		ORDER BY created_at ASC
-		LIMIT $1
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, limit)
+// newOutboxMessage builds a pending outbox message of an order for events
+// recorded by the repositories themselves, i.e. those that depend on state
+// read under lock.
+func newOutboxMessage(msgType string, orderID uuid.UUID, event interface{}) (*outbox.Message, error) {
+	payload, err := json.Marshal(event)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var messages []*outbox.OutboxMessage
-	for rows.Next() {
-		msg := &outbox.OutboxMessage{}
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Type,
-			&msg.Payload,
-			&msg.Status,
-			&msg.CreatedAt,
-			&msg.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		messages = append(messages, msg)
+		return nil, fmt.Errorf("failed to marshal outbox event: %w", err)
 	}
-
-	return messages, nil
+	now := time.Now()
+	return &outbox.Message{
+		ID:            uuid.New(),
+		Type:          msgType,
+		AggregateType: outbox.AggregateOrder,
+		AggregateID:   orderID.String(),
+		Payload:       payload,
+		Status:        "pending",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
 }
 
-func (r *OutboxRepository) MarkAsProcessed(ctx context.Context, id uuid.UUID) error {
+// insertOutboxMessage saves an outbox message within the caller's transaction,
+// tagged with the correlation ID of ctx unless it already has one. If the
+// message triggers a webhook event, a delivery is queued in the same
+// transaction for every active subscription to it.
+func insertOutboxMessage(ctx context.Context, tx *sqlx.Tx, message *outbox.Message) error {
+	if message.CorrelationID == "" {
+		message.CorrelationID = domain.CorrelationID(ctx)
+	}
 	query := `
-		UPDATE outbox_messages
-		SET status = 'processed', updated_at = $1
-		WHERE id = $2
+		INSERT INTO outbox_messages (id, type, aggregate_type, aggregate_id, payload, status, correlation_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
+	_, err := tx.ExecContext(ctx, query,
+		message.ID,
+		message.Type,
+		message.AggregateType,
+		message.AggregateID,
+		message.Payload,
+		message.Status,
+		message.CorrelationID,
+		message.CreatedAt,
+		message.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	eventType, ok := domain.WebhookEventFor(message.Type, message.Payload)
+	if !ok {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		SELECT gen_random_uuid(), s.id, $1, $2, $3, $4, NOW(), $5
+		FROM webhook_subscriptions s
+		WHERE s.active AND $2 = ANY(s.event_types)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		message.ID, eventType, []byte(message.Payload), domain.DeliveryPending, message.CreatedAt,
+	)
 	return err
-} 
\ No newline at end of file
+}
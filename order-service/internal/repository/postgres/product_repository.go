@@ -2,11 +2,17 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
 )
 
+const productColumns = "id, name, description, price, currency, category_id, stock_quantity, reserved_quantity, created_at, updated_at, deleted_at"
+
 type ProductRepository struct {
 	db *sqlx.DB
 }
@@ -17,7 +23,7 @@ func NewProductRepository(db *sqlx.DB) *ProductRepository {
 
 func (r *ProductRepository) GetAll(ctx context.Context) ([]*domain.Product, error) {
 	var products []*domain.Product
-	query := "SELECT id, name, description, price, created_at, updated_at FROM products"
+	query := "SELECT " + productColumns + " FROM products WHERE deleted_at IS NULL ORDER BY id"
 	err := r.db.SelectContext(ctx, &products, query)
 	if err != nil {
 		return nil, err
@@ -27,7 +33,7 @@ func (r *ProductRepository) GetAll(ctx context.Context) ([]*domain.Product, erro
 
 func (r *ProductRepository) GetProductsByIDs(ctx context.Context, ids []int64) ([]*domain.Product, error) {
 	var products []*domain.Product
-	query, args, err := sqlx.In("SELECT id, name, description, price, created_at, updated_at FROM products WHERE id IN (?)", ids)
+	query, args, err := sqlx.In("SELECT "+productColumns+" FROM products WHERE id IN (?) AND deleted_at IS NULL", ids)
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +44,284 @@ func (r *ProductRepository) GetProductsByIDs(ctx context.Context, ids []int64) (
 	}
 	return products, nil
 }
+
+// GetByID returns a product that has not been deleted, or nil if there is none.
+func (r *ProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	product := &domain.Product{}
+	query := "SELECT " + productColumns + " FROM products WHERE id = $1 AND deleted_at IS NULL"
+	err := r.db.GetContext(ctx, product, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (r *ProductRepository) Create(ctx context.Context, product *domain.Product) error {
+	query := `
+		INSERT INTO products (name, description, price, currency, category_id, stock_quantity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + productColumns
+	err := r.db.QueryRowxContext(ctx, query,
+		product.Name, product.Description, product.Price, product.Currency, product.CategoryID, product.StockQuantity,
+	).StructScan(product)
+	if isForeignKeyViolation(err) {
+		return domain.ErrCategoryNotFound
+	}
+	return err
+}
+
+// Update changes the fields set in update in a single statement and returns
+// the product. Nothing is written back from an earlier read: fields left nil
+// keep their current values and a stock adjustment is applied to the current
+// stock. updated_at is maintained by the products_set_updated_at trigger.
+func (r *ProductRepository) Update(ctx context.Context, id int64, update *domain.ProductUpdate) (*domain.Product, error) {
+	var (
+		sets []string
+		args []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if update.Name != nil {
+		sets = append(sets, "name = "+arg(*update.Name))
+	}
+	if update.Description != nil {
+		sets = append(sets, "description = "+arg(*update.Description))
+	}
+	if update.Price != nil {
+		sets = append(sets, "price = "+arg(*update.Price))
+	}
+	if update.Currency != nil {
+		sets = append(sets, "currency = "+arg(*update.Currency))
+	}
+	if update.CategoryID != nil {
+		sets = append(sets, "category_id = "+arg(*update.CategoryID))
+	} else if update.ClearCategory {
+		sets = append(sets, "category_id = NULL")
+	}
+	if update.StockQuantity != nil {
+		sets = append(sets, "stock_quantity = "+arg(*update.StockQuantity))
+	}
+	if update.StockAdjustment != nil {
+		sets = append(sets, "stock_quantity = stock_quantity + "+arg(*update.StockAdjustment))
+	}
+
+	if len(sets) == 0 {
+		product, err := r.GetByID(ctx, id)
+		if err == nil && product == nil {
+			err = domain.ErrProductNotFound
+		}
+		return product, err
+	}
+
+	query := "UPDATE products SET " + strings.Join(sets, ", ") +
+		" WHERE id = " + arg(id) + " AND deleted_at IS NULL RETURNING " + productColumns
+	product := &domain.Product{}
+	err := r.db.QueryRowxContext(ctx, query, args...).StructScan(product)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrProductNotFound
+	}
+	if isForeignKeyViolation(err) {
+		return nil, domain.ErrCategoryNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "products_reserved_within_stock", "products_stock_non_negative":
+			return nil, domain.ErrStockBelowReserved
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// Delete soft-deletes a product.
+func (r *ProductRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE products SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrProductNotFound
+	}
+	return nil
+}
+
+// Search returns a page of non-deleted products matching the filter and the
+// total number of matches.
+func (r *ProductRepository) Search(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, int, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := productSearchConditions(filter, true, arg)
+
+	orderBy := "id"
+	switch filter.Sort {
+	case domain.SortPriceAsc:
+		orderBy = "price ASC, id"
+	case domain.SortPriceDesc:
+		orderBy = "price DESC, id"
+	case domain.SortNewest:
+		orderBy = "created_at DESC, id DESC"
+	default:
+		if filter.Query != "" {
+			orderBy = "ts_rank(search_vector, " + productTSQuery(arg(filter.Query)) + ") DESC, id"
+		}
+	}
+
+	query := "SELECT " + productColumns + ", COUNT(*) OVER () AS total FROM products" +
+		" WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + orderBy +
+		" LIMIT " + arg(filter.Limit) + " OFFSET " + arg(filter.Offset)
+
+	var rows []struct {
+		domain.Product
+		Total int `db:"total"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, err
+	}
+
+	products := make([]*domain.Product, len(rows))
+	total := 0
+	for i := range rows {
+		products[i] = &rows[i].Product
+		total = rows[i].Total
+	}
+	// Страница за пределами выборки: общее количество считаем отдельно
+	if len(rows) == 0 && filter.Offset > 0 {
+		var countArgs []interface{}
+		countArg := func(v interface{}) string {
+			countArgs = append(countArgs, v)
+			return fmt.Sprintf("$%d", len(countArgs))
+		}
+		countQuery := "SELECT COUNT(*) FROM products WHERE " +
+			strings.Join(productSearchConditions(filter, true, countArg), " AND ")
+		if err := r.db.GetContext(ctx, &total, countQuery, countArgs...); err != nil {
+			return nil, 0, err
+		}
+	}
+	return products, total, nil
+}
+
+// CountByCategory returns the number of products matching the filter per
+// category they are directly assigned to. The category filter is ignored.
+func (r *ProductRepository) CountByCategory(ctx context.Context, filter domain.ProductFilter) (map[int64]int, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := "SELECT category_id, COUNT(*) AS count FROM products" +
+		" WHERE category_id IS NOT NULL AND " + strings.Join(productSearchConditions(filter, false, arg), " AND ") +
+		" GROUP BY category_id"
+
+	var rows []struct {
+		CategoryID int64 `db:"category_id"`
+		Count      int   `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *ProductRepository) GetCategories(ctx context.Context) ([]*domain.Category, error) {
+	categories := []*domain.Category{}
+	query := `SELECT id, name, slug, parent_id, created_at FROM categories ORDER BY id`
+	if err := r.db.SelectContext(ctx, &categories, query); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoryBySlug returns a category, or nil if there is none.
+func (r *ProductRepository) GetCategoryBySlug(ctx context.Context, slug string) (*domain.Category, error) {
+	category := &domain.Category{}
+	query := `SELECT id, name, slug, parent_id, created_at FROM categories WHERE slug = $1`
+	err := r.db.GetContext(ctx, category, query, slug)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (r *ProductRepository) CreateCategory(ctx context.Context, category *domain.Category) error {
+	query := `
+		INSERT INTO categories (name, slug, parent_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowxContext(ctx, query, category.Name, category.Slug, category.ParentID).
+		Scan(&category.ID, &category.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return domain.ErrCategoryNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		verr := &domain.ValidationError{}
+		verr.Add("slug", "is already taken")
+		return verr
+	}
+	return err
+}
+
+// productSearchConditions builds the WHERE conditions of a product search.
+// arg registers a query argument and returns its placeholder.
+func productSearchConditions(filter domain.ProductFilter, withCategory bool, arg func(interface{}) string) []string {
+	conditions := []string{"deleted_at IS NULL"}
+	if filter.Query != "" {
+		conditions = append(conditions, "search_vector @@ "+productTSQuery(arg(filter.Query)))
+	}
+	if withCategory && filter.CategoryID != nil {
+		conditions = append(conditions, `category_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM categories WHERE id = `+arg(*filter.CategoryID)+`
+				UNION ALL
+				SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+			)
+			SELECT id FROM subtree
+		)`)
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "currency = "+arg(filter.Currency))
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "price >= "+arg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+arg(*filter.MaxPrice))
+	}
+	return conditions
+}
+
+// productTSQuery matches the search query with both Russian and English stemming.
+func productTSQuery(placeholder string) string {
+	return "(websearch_to_tsquery('russian', " + placeholder + ") || websearch_to_tsquery('english', " + placeholder + "))"
+}
+
+func isForeignKeyViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23503"
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const promoColumns = `id, code, description, discount_type, percent_off, amount_off, currency,
+	min_order_amount, max_redemptions, max_redemptions_per_user, redemptions_count,
+	starts_at, ends_at, active, created_at, updated_at`
+
+type PromoRepository struct {
+	db *sqlx.DB
+}
+
+func NewPromoRepository(db *sqlx.DB) *PromoRepository {
+	return &PromoRepository{db: db}
+}
+
+// GetByCode looks a promo code up case-insensitively; codes are stored upper-cased.
+func (r *PromoRepository) GetByCode(ctx context.Context, code string) (*domain.PromoCode, error) {
+	promo := &domain.PromoCode{}
+	err := r.db.GetContext(ctx, promo, `SELECT `+promoColumns+` FROM promo_codes WHERE code = $1`, strings.ToUpper(code))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrPromoCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadRestrictions(ctx, []*domain.PromoCode{promo}); err != nil {
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (r *PromoRepository) GetAll(ctx context.Context) ([]*domain.PromoCode, error) {
+	var promos []*domain.PromoCode
+	if err := r.db.SelectContext(ctx, &promos, `SELECT `+promoColumns+` FROM promo_codes ORDER BY id`); err != nil {
+		return nil, err
+	}
+	if err := r.loadRestrictions(ctx, promos); err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+// Create stores a promo code together with its product and category restrictions.
+func (r *PromoRepository) Create(ctx context.Context, promo *domain.PromoCode) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	promo.Code = strings.ToUpper(promo.Code)
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO promo_codes (code, description, discount_type, percent_off, amount_off, currency,
+			min_order_amount, max_redemptions, max_redemptions_per_user, starts_at, ends_at, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+promoColumns,
+		promo.Code, promo.Description, promo.Type, promo.PercentOff, promo.AmountOff, promo.Currency,
+		promo.MinOrderAmount, promo.MaxRedemptions, promo.MaxRedemptionsPerUser, promo.StartsAt, promo.EndsAt, promo.Active,
+	).StructScan(promo)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		verr := &domain.ValidationError{}
+		verr.Add("code", "already exists")
+		return verr
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, productID := range promo.ProductIDs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO promo_code_products (promo_code_id, product_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, promo.ID, productID)
+		if isForeignKeyViolation(err) {
+			return domain.ErrProductNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, categoryID := range promo.CategoryIDs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO promo_code_categories (promo_code_id, category_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, promo.ID, categoryID)
+		if isForeignKeyViolation(err) {
+			return domain.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CountUserRedemptions returns how many orders of the user redeemed the code.
+func (r *PromoRepository) CountUserRedemptions(ctx context.Context, promoID int64, userID string) (int, error) {
+	var count int
+	err := r.db.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM promo_redemptions WHERE promo_code_id = $1 AND user_id = $2`, promoID, userID)
+	return count, err
+}
+
+func (r *PromoRepository) loadRestrictions(ctx context.Context, promos []*domain.PromoCode) error {
+	if len(promos) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(promos))
+	byID := make(map[int64]*domain.PromoCode, len(promos))
+	for i, promo := range promos {
+		ids[i] = promo.ID
+		byID[promo.ID] = promo
+		promo.ProductIDs = []int64{}
+		promo.CategoryIDs = []int64{}
+	}
+
+	var restrictions []struct {
+		PromoCodeID int64  `db:"promo_code_id"`
+		Kind        string `db:"kind"`
+		RefID       int64  `db:"ref_id"`
+	}
+	err := r.db.SelectContext(ctx, &restrictions, `
+		SELECT promo_code_id, 'product' AS kind, product_id AS ref_id
+		FROM promo_code_products WHERE promo_code_id = ANY($1)
+		UNION ALL
+		SELECT promo_code_id, 'category', category_id
+		FROM promo_code_categories WHERE promo_code_id = ANY($1)
+		ORDER BY 1, 2, 3
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	for _, restriction := range restrictions {
+		promo := byID[restriction.PromoCodeID]
+		if restriction.Kind == "product" {
+			promo.ProductIDs = append(promo.ProductIDs, restriction.RefID)
+		} else {
+			promo.CategoryIDs = append(promo.CategoryIDs, restriction.RefID)
+		}
+	}
+	return nil
+}
+
+// redeemPromoCodesTx records the redemption of every promo code applied to
+// the order. The promo code row is locked by the UPDATE, so concurrent
+// orders cannot exceed the global or the per-user limit.
+func redeemPromoCodesTx(ctx context.Context, tx *sqlx.Tx, order *domain.Order) error {
+	for i := range order.Discounts {
+		discount := &order.Discounts[i]
+		discount.OrderID = order.ID
+
+		if discount.PromoCodeID != nil {
+			var maxPerUser sql.NullInt64
+			err := tx.QueryRowxContext(ctx, `
+				UPDATE promo_codes
+				SET redemptions_count = redemptions_count + 1
+				WHERE id = $1 AND active
+					AND (max_redemptions IS NULL OR redemptions_count < max_redemptions)
+					AND (starts_at IS NULL OR starts_at <= NOW())
+					AND (ends_at IS NULL OR ends_at > NOW())
+				RETURNING max_redemptions_per_user`,
+				*discount.PromoCodeID,
+			).Scan(&maxPerUser)
+			if err == sql.ErrNoRows {
+				return domain.ErrPromoCodeExhausted
+			}
+			if err != nil {
+				return err
+			}
+
+			if maxPerUser.Valid {
+				var used int64
+				err := tx.QueryRowxContext(ctx,
+					`SELECT COUNT(*) FROM promo_redemptions WHERE promo_code_id = $1 AND user_id = $2`,
+					*discount.PromoCodeID, order.UserID,
+				).Scan(&used)
+				if err != nil {
+					return err
+				}
+				if used >= maxPerUser.Int64 {
+					return domain.ErrPromoCodeExhausted
+				}
+			}
+
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO promo_redemptions (promo_code_id, order_id, user_id) VALUES ($1, $2, $3)`,
+				*discount.PromoCodeID, order.ID, order.UserID,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.QueryRowxContext(ctx, `
+			INSERT INTO order_discounts (order_id, promo_code_id, code, description, amount)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id`,
+			order.ID, discount.PromoCodeID, discount.Code, discount.Description, discount.Amount,
+		).Scan(&discount.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releasePromoCodesTx returns the redemptions of the order's promo codes, so
+// that a cancelled order, whether unpaid or refunded in full, does not use up
+// the limits. The order's discounts are kept as a record of what was applied.
+func releasePromoCodesTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH released AS (
+			DELETE FROM promo_redemptions WHERE order_id = $1
+			RETURNING promo_code_id
+		)
+		UPDATE promo_codes p
+		SET redemptions_count = p.redemptions_count - r.n
+		FROM (SELECT promo_code_id, COUNT(*) AS n FROM released GROUP BY promo_code_id) r
+		WHERE p.id = r.promo_code_id`,
+		orderID,
+	)
+	return err
+}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
+)
+
+const returnColumns = "id, order_id, user_id, status, reason, refund_amount, currency, created_at, updated_at"
+
+type ReturnRepository struct {
+	db *sqlx.DB
+}
+
+func NewReturnRepository(db *sqlx.DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+// Create prices and stores a return of a FINISHED order. The order row is
+// locked for the duration, so concurrent returns of the same order cannot
+// take back more items than were ordered.
+func (r *ReturnRepository) Create(ctx context.Context, ret *domain.OrderReturn) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	order := &domain.Order{}
+	err = tx.GetContext(ctx, order, `SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, ret.OrderID)
+	if err == sql.ErrNoRows {
+		return domain.ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if order.Status != domain.StatusFinished {
+		return fmt.Errorf("%w: order is %s", domain.ErrOrderNotReturnable, order.Status)
+	}
+
+	err = tx.SelectContext(ctx, &order.Items,
+		`SELECT `+orderItemColumns+` FROM order_items WHERE order_id = $1 ORDER BY id`,
+		order.ID)
+	if err != nil {
+		return err
+	}
+
+	var returnedRows []struct {
+		OrderItemID int64 `db:"order_item_id"`
+		Quantity    int   `db:"quantity"`
+	}
+	err = tx.SelectContext(ctx, &returnedRows, `
+		SELECT ri.order_item_id, SUM(ri.quantity) AS quantity
+		FROM order_return_items ri
+		JOIN order_returns r ON r.id = ri.return_id
+		WHERE r.order_id = $1
+		GROUP BY ri.order_item_id`, order.ID)
+	if err != nil {
+		return err
+	}
+	returned := make(map[int64]int, len(returnedRows))
+	for _, row := range returnedRows {
+		returned[row.OrderItemID] = row.Quantity
+	}
+
+	var refunded money.Amount
+	err = tx.GetContext(ctx, &refunded, `SELECT COALESCE(SUM(refund_amount), 0) FROM order_returns WHERE order_id = $1`, order.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := domain.PriceReturn(order, returned, refunded, ret); err != nil {
+		return err
+	}
+	ret.UserID = order.UserID
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO order_returns (id, order_id, user_id, status, reason, refund_amount, currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING created_at, updated_at`,
+		ret.ID, ret.OrderID, ret.UserID, ret.Status, ret.Reason, ret.RefundAmount, ret.Currency,
+	).Scan(&ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.ReturnID = ret.ID
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_return_items (return_id, order_item_id, product_id, quantity, refund_amount)
+			VALUES ($1, $2, $3, $4, $5)`,
+			ret.ID, item.OrderItemID, item.ProductID, item.Quantity, item.RefundAmount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ReturnRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.OrderReturn, error) {
+	ret := &domain.OrderReturn{}
+	err := r.db.GetContext(ctx, ret, `SELECT `+returnColumns+` FROM order_returns WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrReturnNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadItems(ctx, []*domain.OrderReturn{ret}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GetByOrderID returns the returns of an order, oldest first.
+func (r *ReturnRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.OrderReturn, error) {
+	returns := []*domain.OrderReturn{}
+	err := r.db.SelectContext(ctx, &returns,
+		`SELECT `+returnColumns+` FROM order_returns WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadItems(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// ChangeStatus moves a return to status if the transition is allowed and
+// saves the outbox messages in the same transaction.
+func (r *ReturnRepository) ChangeStatus(ctx context.Context, id uuid.UUID, status domain.ReturnStatus, outboxMsgs ...*outbox.Message) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	var from domain.ReturnStatus
+	err = tx.QueryRowxContext(ctx, `SELECT status FROM order_returns WHERE id = $1 FOR UPDATE`, id).Scan(&from)
+	if err == sql.ErrNoRows {
+		return domain.ErrReturnNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !from.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", domain.ErrInvalidReturnTransition, from, status)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE order_returns SET status = $1 WHERE id = $2`, status, id); err != nil {
+		return err
+	}
+
+	for _, msg := range outboxMsgs {
+		if err := insertOutboxMessage(ctx, tx, msg); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ReturnRepository) loadItems(ctx context.Context, returns []*domain.OrderReturn) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(returns))
+	byID := make(map[uuid.UUID]*domain.OrderReturn, len(returns))
+	for i, ret := range returns {
+		ids[i] = ret.ID
+		byID[ret.ID] = ret
+		ret.Items = []domain.ReturnItem{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT return_id, order_item_id, product_id, quantity, refund_amount
+		FROM order_return_items
+		WHERE return_id IN (?)
+		ORDER BY return_id, order_item_id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	query = r.db.Rebind(query)
+
+	var items []domain.ReturnItem
+	if err := r.db.SelectContext(ctx, &items, query, args...); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if ret, ok := byID[item.ReturnID]; ok {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+	return nil
+}
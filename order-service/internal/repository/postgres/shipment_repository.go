@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const shipmentColumns = `id, order_id, status, carrier, tracking_number, shipped_at, delivered_at, returned_at,
+	created_at, updated_at`
+
+type ShipmentRepository struct {
+	db *sqlx.DB
+}
+
+func NewShipmentRepository(db *sqlx.DB) *ShipmentRepository {
+	return &ShipmentRepository{db: db}
+}
+
+// Create packs a shipment of a FINISHED order that has a shipping address
+// and saves the shipment_status_changed outbox message. The order row is
+// locked for the duration, so concurrent shipments of the same order cannot
+// pack more items than were ordered.
+func (r *ShipmentRepository) Create(ctx context.Context, s *domain.Shipment) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	order := &domain.Order{}
+	err = tx.GetContext(ctx, order, `SELECT `+orderColumns+` FROM orders WHERE id = $1 FOR UPDATE`, s.OrderID)
+	if err == sql.ErrNoRows {
+		return domain.ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if order.Status != domain.StatusFinished {
+		return fmt.Errorf("%w: order is %s", domain.ErrOrderNotShippable, order.Status)
+	}
+
+	var hasAddress bool
+	err = tx.GetContext(ctx, &hasAddress, `SELECT EXISTS (SELECT 1 FROM order_shipping_addresses WHERE order_id = $1)`, order.ID)
+	if err != nil {
+		return err
+	}
+	if !hasAddress {
+		return fmt.Errorf("%w: order has no shipping address", domain.ErrOrderNotShippable)
+	}
+
+	err = tx.SelectContext(ctx, &order.Items,
+		`SELECT `+orderItemColumns+` FROM order_items WHERE order_id = $1 ORDER BY id`,
+		order.ID)
+	if err != nil {
+		return err
+	}
+
+	var shippedRows []struct {
+		OrderItemID int64 `db:"order_item_id"`
+		Quantity    int   `db:"quantity"`
+	}
+	err = tx.SelectContext(ctx, &shippedRows, `
+		SELECT si.order_item_id, SUM(si.quantity) AS quantity
+		FROM shipment_items si
+		JOIN shipments s ON s.id = si.shipment_id
+		WHERE s.order_id = $1
+		GROUP BY si.order_item_id`, order.ID)
+	if err != nil {
+		return err
+	}
+	shipped := make(map[int64]int, len(shippedRows))
+	for _, row := range shippedRows {
+		shipped[row.OrderItemID] = row.Quantity
+	}
+
+	if err := domain.PlanShipment(order, shipped, s); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO shipments (id, order_id, status, carrier, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING created_at, updated_at`,
+		s.ID, s.OrderID, s.Status, s.Carrier,
+	).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range s.Items {
+		item := &s.Items[i]
+		item.ShipmentID = s.ID
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO shipment_items (shipment_id, order_item_id, quantity) VALUES ($1, $2, $3)`,
+			s.ID, item.OrderItemID, item.Quantity,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := insertShipmentMessage(ctx, tx, s, order.UserID, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ShipmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Shipment, error) {
+	s := &domain.Shipment{}
+	err := r.db.GetContext(ctx, s, `SELECT `+shipmentColumns+` FROM shipments WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrShipmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := loadShipmentItems(ctx, r.db, []*domain.Shipment{s}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetByOrderID returns the shipments of an order, oldest first.
+func (r *ShipmentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.Shipment, error) {
+	shipments := []*domain.Shipment{}
+	err := r.db.SelectContext(ctx, &shipments,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadShipmentItems(ctx, r.db, shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// ChangeStatus moves a shipment to change.To if the transition is allowed,
+// stamps the time of the transition and saves the shipment_status_changed
+// outbox message in the same transaction.
+func (r *ShipmentRepository) ChangeStatus(ctx context.Context, change domain.ShipmentChange) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	s := &domain.Shipment{}
+	err = tx.GetContext(ctx, s, `SELECT `+shipmentColumns+` FROM shipments WHERE id = $1 FOR UPDATE`, change.ShipmentID)
+	if err == sql.ErrNoRows {
+		return domain.ErrShipmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	from := s.Status
+	if !from.CanTransitionTo(change.To) {
+		return fmt.Errorf("%w: %s -> %s", domain.ErrInvalidShipmentTransition, from, change.To)
+	}
+
+	query := `
+		UPDATE shipments
+		SET status = $1,
+		    carrier = CASE WHEN $2 = '' THEN carrier ELSE $2 END,
+		    tracking_number = CASE WHEN $3 = '' THEN tracking_number ELSE $3 END,
+		    shipped_at = CASE WHEN $1 = 'SHIPPED' THEN NOW() ELSE shipped_at END,
+		    delivered_at = CASE WHEN $1 = 'DELIVERED' THEN NOW() ELSE delivered_at END,
+		    returned_at = CASE WHEN $1 = 'RETURNED' THEN NOW() ELSE returned_at END
+		WHERE id = $4
+		RETURNING ` + shipmentColumns
+	err = tx.GetContext(ctx, s, query, change.To, change.Carrier, change.TrackingNumber, change.ShipmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := loadShipmentItems(ctx, tx, []*domain.Shipment{s}); err != nil {
+		return err
+	}
+	var userID string
+	if err := tx.GetContext(ctx, &userID, `SELECT user_id FROM orders WHERE id = $1`, s.OrderID); err != nil {
+		return err
+	}
+	if err := insertShipmentMessage(ctx, tx, s, userID, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertShipmentMessage(ctx context.Context, tx *sqlx.Tx, s *domain.Shipment, userID string, from domain.ShipmentStatus) error {
+	msg, err := newOutboxMessage("shipment_status_changed", s.OrderID, domain.ShipmentStatusChangedEvent{
+		ShipmentID:     s.ID,
+		OrderID:        s.OrderID,
+		UserID:         userID,
+		FromStatus:     from,
+		ToStatus:       s.Status,
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		Items:          s.Items,
+	})
+	if err != nil {
+		return err
+	}
+	return insertOutboxMessage(ctx, tx, msg)
+}
+
+func loadShipmentItems(ctx context.Context, q sqlx.QueryerContext, shipments []*domain.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(shipments))
+	byID := make(map[uuid.UUID]*domain.Shipment, len(shipments))
+	for i, s := range shipments {
+		ids[i] = s.ID
+		byID[s.ID] = s
+		s.Items = []domain.ShipmentItem{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT shipment_id, order_item_id, quantity
+		FROM shipment_items
+		WHERE shipment_id IN (?)
+		ORDER BY shipment_id, order_item_id
+	`, ids)
+	if err != nil {
+		return err
+	}
+
+	var items []domain.ShipmentItem
+	if err := sqlx.SelectContext(ctx, q, &items, sqlx.Rebind(sqlx.DOLLAR, query), args...); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if s, ok := byID[item.ShipmentID]; ok {
+			s.Items = append(s.Items, item)
+		}
+	}
+	return nil
+}
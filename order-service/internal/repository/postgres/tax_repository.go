@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const taxRateColumns = "id, category_id, country, region, rate, created_at"
+
+type TaxRepository struct {
+	db *sqlx.DB
+}
+
+func NewTaxRepository(db *sqlx.DB) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+func (r *TaxRepository) GetAll(ctx context.Context) ([]*domain.TaxRate, error) {
+	rates := []*domain.TaxRate{}
+	err := r.db.SelectContext(ctx, &rates,
+		`SELECT `+taxRateColumns+` FROM tax_rates ORDER BY country, region, category_id NULLS FIRST, id`)
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// GetByCountry returns every rate of the country, whatever the region and category.
+func (r *TaxRepository) GetByCountry(ctx context.Context, country string) ([]*domain.TaxRate, error) {
+	rates := []*domain.TaxRate{}
+	err := r.db.SelectContext(ctx, &rates,
+		`SELECT `+taxRateColumns+` FROM tax_rates WHERE country = $1 ORDER BY id`, strings.ToUpper(country))
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *TaxRepository) Create(ctx context.Context, rate *domain.TaxRate) error {
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO tax_rates (category_id, country, region, rate)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+taxRateColumns,
+		rate.CategoryID, rate.Country, rate.Region, rate.Rate,
+	).StructScan(rate)
+	if isForeignKeyViolation(err) {
+		return domain.ErrCategoryNotFound
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		verr := &domain.ValidationError{}
+		verr.Add("rate", "a rate for this category and destination already exists")
+		return verr
+	}
+	return err
+}
+
+func (r *TaxRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tax_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrTaxRateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// The secret is never read back for the API; only the dispatcher needs it.
+const webhookSubscriptionColumns = `id, url, event_types, description, active, consecutive_failures,
+	disabled_at, disabled_reason, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_attempt_at, delivered_at, created_at`
+
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// webhookSubscriptionRow scans event_types, which sqlx cannot map to a slice.
+type webhookSubscriptionRow struct {
+	domain.WebhookSubscription
+	EventTypes pq.StringArray `db:"event_types"`
+}
+
+func (row *webhookSubscriptionRow) subscription() *domain.WebhookSubscription {
+	sub := row.WebhookSubscription
+	sub.EventTypes = make([]domain.WebhookEventType, len(row.EventTypes))
+	for i, eventType := range row.EventTypes {
+		sub.EventTypes[i] = domain.WebhookEventType(eventType)
+	}
+	return &sub
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, eventType := range sub.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO webhook_subscriptions (id, url, event_types, description, secret, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at`,
+		sub.ID, sub.URL, pq.StringArray(eventTypes), sub.Description, sub.Secret, sub.Active,
+	).Scan(&sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	row := &webhookSubscriptionRow{}
+	err := r.db.GetContext(ctx, row, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrWebhookSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.subscription(), nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	var rows []*webhookSubscriptionRow
+	if err := r.db.SelectContext(ctx, &rows, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`); err != nil {
+		return nil, err
+	}
+	subs := make([]*domain.WebhookSubscription, len(rows))
+	for i, row := range rows {
+		subs[i] = row.subscription()
+	}
+	return subs, nil
+}
+
+// DeleteSubscription removes a subscription together with its deliveries.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return subscriptionAffected(res)
+}
+
+// EnableSubscription re-activates a subscription and resets its failure count.
+// Deliveries queued before it was disabled are retried.
+func (r *WebhookRepository) EnableSubscription(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET active = TRUE, consecutive_failures = 0, disabled_at = NULL, disabled_reason = ''
+		WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return subscriptionAffected(res)
+}
+
+// GetDeliveries returns the latest deliveries of a subscription, newest first,
+// with their attempts.
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	deliveries := []*domain.WebhookDelivery{}
+	err := r.db.SelectContext(ctx, &deliveries, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]uuid.UUID, len(deliveries))
+	byID := make(map[uuid.UUID]*domain.WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+		byID[delivery.ID] = delivery
+	}
+	query, args, err := sqlx.In(`
+		SELECT id, delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id IN (?)
+		ORDER BY delivery_id, id
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var attempts []domain.WebhookAttempt
+	if err := r.db.SelectContext(ctx, &attempts, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, attempt := range attempts {
+		if delivery, ok := byID[attempt.DeliveryID]; ok {
+			delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+		}
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries of active
+// subscriptions that are due, and pushes their next attempt lease into the
+// future so that other dispatchers skip them meanwhile. A delivery whose
+// dispatcher dies is picked up again once the lease runs out.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	deliveries := []*domain.WebhookDelivery{}
+	err := r.db.SelectContext(ctx, &deliveries, `
+		WITH due AS (
+			SELECT d.id
+			FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = $1 AND d.next_attempt_at <= NOW() AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT $2
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $3)
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.last_attempt_at, d.delivered_at, d.created_at, s.url, s.secret`,
+		domain.DeliveryPending, limit, lease.Seconds(),
+	)
+	return deliveries, err
+}
+
+// RecordAttempt stores a delivery attempt and its outcome. A successful attempt
+// marks the delivery delivered and resets the subscription's failure count.
+// A failed one schedules the delivery for retryAt, or marks it failed if
+// retryAt is nil, and disables the subscription after maxFailures failures
+// in a row.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, attempt *domain.WebhookAttempt, retryAt *time.Time, maxFailures int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Rollback is ignored if tx is committed
+
+	var subscriptionID uuid.UUID
+	err = tx.QueryRowxContext(ctx,
+		`SELECT subscription_id, attempts + 1 FROM webhook_deliveries WHERE id = $1 FOR UPDATE`,
+		attempt.DeliveryID,
+	).Scan(&subscriptionID, &attempt.Attempt)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMS,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if attempt.Succeeded() {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, last_attempt_at = NOW(), delivered_at = NOW()
+			WHERE id = $1`, attempt.DeliveryID, domain.DeliveryDelivered, attempt.Attempt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0`, subscriptionID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	status, nextAttemptAt := domain.DeliveryPending, time.Now()
+	if retryAt == nil {
+		status = domain.DeliveryFailed
+	} else {
+		nextAttemptAt = *retryAt
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_attempt_at = NOW(), next_attempt_at = $4
+		WHERE id = $1`, attempt.DeliveryID, status, attempt.Attempt, nextAttemptAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_subscriptions
+		SET consecutive_failures = consecutive_failures + 1,
+		    active = active AND consecutive_failures + 1 < $2,
+		    disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $2 THEN NOW() ELSE disabled_at END,
+		    disabled_reason = CASE WHEN active AND consecutive_failures + 1 >= $2 THEN $3 ELSE disabled_reason END
+		WHERE id = $1`,
+		subscriptionID, maxFailures, fmt.Sprintf("%d consecutive delivery failures", maxFailures))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func subscriptionAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrWebhookSubscriptionNotFound
+	}
+	return nil
+}
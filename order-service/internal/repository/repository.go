@@ -2,22 +2,98 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
+// OutboxRepository saves messages for the outbox relay.
+type OutboxRepository interface {
+	Save(ctx context.Context, message *outbox.Message) error
+}
+
 type OrderRepository interface {
-	Create(ctx context.Context, order *domain.Order) error
-	CreateWithOutbox(ctx context.Context, order *domain.Order, outboxMsg *outbox.OutboxMessage) error
+	CreateWithOutbox(ctx context.Context, order *domain.Order, outboxMsg *outbox.Message) error
+	// CreateFromCart creates the order like CreateWithOutbox and removes the
+	// ordered lines from the user's cart in the same transaction.
+	CreateFromCart(ctx context.Context, order *domain.Order, outboxMsg *outbox.Message) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Order, error)
 	GetByUserID(ctx context.Context, userID string) ([]*domain.Order, error)
 	GetAll(ctx context.Context) ([]*domain.Order, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.OrderStatus) error
+	List(ctx context.Context, filter domain.OrderFilter) ([]*domain.Order, error)
+	// GetStale returns orders, without items, that have been in status for longer than olderThan.
+	GetStale(ctx context.Context, status domain.OrderStatus, olderThan time.Duration, limit int) ([]*domain.Order, error)
+	ChangeStatus(ctx context.Context, change domain.StatusChange, outboxMsgs ...*outbox.Message) error
+	GetStatusHistory(ctx context.Context, orderID uuid.UUID) ([]*domain.StatusHistoryEntry, error)
+	GetUserStatusChanges(ctx context.Context, userID string, afterID int64, limit int) ([]*domain.StatusHistoryEntry, error)
+	GetLastUserStatusChangeID(ctx context.Context, userID string) (int64, error)
 }
 
 type ProductRepository interface {
 	GetProductsByIDs(ctx context.Context, ids []int64) ([]*domain.Product, error)
 	GetAll(ctx context.Context) ([]*domain.Product, error)
+	GetByID(ctx context.Context, id int64) (*domain.Product, error)
+	Create(ctx context.Context, product *domain.Product) error
+	Update(ctx context.Context, id int64, update *domain.ProductUpdate) (*domain.Product, error)
+	Delete(ctx context.Context, id int64) error
+	Search(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, int, error)
+	CountByCategory(ctx context.Context, filter domain.ProductFilter) (map[int64]int, error)
+	GetCategories(ctx context.Context) ([]*domain.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*domain.Category, error)
+	CreateCategory(ctx context.Context, category *domain.Category) error
+}
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, key string, statusCode int, body []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type CartRepository interface {
+	GetItems(ctx context.Context, userID string) ([]*domain.CartItem, error)
+	SetItem(ctx context.Context, userID string, productID int64, quantity int) error
+	RemoveItem(ctx context.Context, userID string, productID int64) error
+	Clear(ctx context.Context, userID string) error
+}
+
+type PromoRepository interface {
+	GetByCode(ctx context.Context, code string) (*domain.PromoCode, error)
+	GetAll(ctx context.Context) ([]*domain.PromoCode, error)
+	Create(ctx context.Context, promo *domain.PromoCode) error
+	CountUserRedemptions(ctx context.Context, promoID int64, userID string) (int, error)
+}
+
+type ReturnRepository interface {
+	// Create prices the return against the order and stores it; the order must be FINISHED.
+	Create(ctx context.Context, ret *domain.OrderReturn) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.OrderReturn, error)
+	GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.OrderReturn, error)
+	ChangeStatus(ctx context.Context, id uuid.UUID, status domain.ReturnStatus, outboxMsgs ...*outbox.Message) error
+}
+
+type ShipmentRepository interface {
+	// Create packs the shipment and records its outbox event; the order must be FINISHED and have a shipping address.
+	Create(ctx context.Context, s *domain.Shipment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Shipment, error)
+	GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*domain.Shipment, error)
+	ChangeStatus(ctx context.Context, change domain.ShipmentChange) error
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *domain.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	EnableSubscription(ctx context.Context, id uuid.UUID) error
+	GetDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+type TaxRepository interface {
+	GetAll(ctx context.Context) ([]*domain.TaxRate, error)
+	GetByCountry(ctx context.Context, country string) ([]*domain.TaxRate, error)
+	Create(ctx context.Context, rate *domain.TaxRate) error
+	Delete(ctx context.Context, id int64) error
 }
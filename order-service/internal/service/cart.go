@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// GetCart returns the user's cart priced at the current product prices.
+func (s *Service) GetCart(ctx context.Context, userID string) (*domain.Cart, error) {
+	items, err := s.cartRepo.GetItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return priceCart(userID, items)
+}
+
+// SetCartItem sets the quantity of a product in the cart; zero removes it.
+func (s *Service) SetCartItem(ctx context.Context, userID string, req *CartItemRequest) (*domain.Cart, error) {
+	verr := &domain.ValidationError{}
+	if req.ProductID <= 0 {
+		verr.Add("product_id", "is required")
+	}
+	if req.Quantity < 0 || req.Quantity > domain.MaxCartItemQuantity {
+		verr.Add("quantity", fmt.Sprintf("must be between 0 and %d", domain.MaxCartItemQuantity))
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if req.Quantity == 0 {
+		err = s.cartRepo.RemoveItem(ctx, userID, req.ProductID)
+	} else {
+		err = s.cartRepo.SetItem(ctx, userID, req.ProductID, req.Quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// RemoveCartItem removes a product from the cart.
+func (s *Service) RemoveCartItem(ctx context.Context, userID string, productID int64) (*domain.Cart, error) {
+	if err := s.cartRepo.RemoveItem(ctx, userID, productID); err != nil {
+		return nil, err
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// ClearCart removes all items from the cart.
+func (s *Service) ClearCart(ctx context.Context, userID string) error {
+	return s.cartRepo.Clear(ctx, userID)
+}
+
+// CheckoutCart turns the cart into an order through the regular order
+// creation flow. The ordered lines are removed from the cart in the same
+// transaction as the order is created. The promo code and shipping
+// address of checkout are optional.
+func (s *Service) CheckoutCart(ctx context.Context, userID string, checkout *CheckoutRequest) (*domain.Order, error) {
+	items, err := s.cartRepo.GetItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrCartEmpty
+	}
+
+	req := &CreateOrderRequest{
+		UserID:          userID,
+		Items:           make([]CreateOrderItem, len(items)),
+		PromoCode:       checkout.PromoCode,
+		ShippingAddress: checkout.ShippingAddress,
+	}
+	for i, item := range items {
+		req.Items[i] = CreateOrderItem{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+
+	order, outboxMsg, err := s.prepareOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orderRepo.CreateFromCart(ctx, order, outboxMsg); err != nil {
+		return nil, fmt.Errorf("failed to create order from cart: %w", err)
+	}
+	return order, nil
+}
+
+// priceCart builds the cart view. Lines that cannot be ordered are kept in
+// the cart with a problem description and excluded from the total.
+func priceCart(userID string, items []*domain.CartItem) (*domain.Cart, error) {
+	cart := &domain.Cart{UserID: userID, Items: make([]domain.CartLine, len(items))}
+	for _, item := range items {
+		if !item.ProductDeleted {
+			cart.Currency = item.Currency
+			break
+		}
+	}
+
+	for i, item := range items {
+		line := domain.CartLine{
+			ProductID:         item.ProductID,
+			Name:              item.ProductName,
+			Quantity:          item.Quantity,
+			UnitPrice:         item.Price,
+			Currency:          item.Currency,
+			AvailableQuantity: item.AvailableQuantity,
+		}
+		lineTotal, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		line.LineTotal = lineTotal
+
+		switch {
+		case item.ProductDeleted:
+			line.Problem = "product is no longer available"
+		case item.Currency != cart.Currency:
+			line.Problem = "product is priced in " + string(item.Currency) + ", cart is in " + string(cart.Currency)
+		case item.Quantity > item.AvailableQuantity:
+			line.Problem = fmt.Sprintf("only %d in stock", max(item.AvailableQuantity, 0))
+		}
+		if line.Problem == "" {
+			cart.Total += line.LineTotal
+		}
+		cart.Items[i] = line
+	}
+
+	cart.CanCheckout = len(items) > 0
+	for _, line := range cart.Items {
+		if line.Problem != "" {
+			cart.CanCheckout = false
+		}
+	}
+	return cart, nil
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+// maxProductPrice is the largest price that fits the DECIMAL(10, 2) column.
+const maxProductPrice = money.Amount(9999999999)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SearchProducts returns a page of products matching the filter along with
+// category facets. category may be either a category ID or its slug.
+func (s *Service) SearchProducts(ctx context.Context, filter domain.ProductFilter, category string) (*domain.ProductSearchResult, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultProductPageSize
+	}
+	if filter.Limit > domain.MaxProductPageSize {
+		filter.Limit = domain.MaxProductPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	categories, err := s.productRepo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if category != "" {
+		id, err := resolveCategory(categories, category)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryID = &id
+	}
+
+	products, total, err := s.productRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if products == nil {
+		products = []*domain.Product{}
+	}
+
+	counts, err := s.productRepo.CountByCategory(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ProductSearchResult{
+		Items:  products,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+		Facets: buildCategoryFacets(categories, counts),
+	}, nil
+}
+
+// ListCategories returns all categories; the hierarchy is given by parent_id.
+func (s *Service) ListCategories(ctx context.Context) ([]*domain.Category, error) {
+	return s.productRepo.GetCategories(ctx)
+}
+
+// CreateCategory adds a category to the catalog.
+func (s *Service) CreateCategory(ctx context.Context, req *CategoryRequest) (*domain.Category, error) {
+	verr := &domain.ValidationError{}
+	if req.Name == "" {
+		verr.Add("name", "is required")
+	}
+	if !slugPattern.MatchString(req.Slug) {
+		verr.Add("slug", "must consist of lowercase letters, digits and dashes")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	category := &domain.Category{Name: req.Name, Slug: req.Slug, ParentID: req.ParentID}
+	if err := s.productRepo.CreateCategory(ctx, category); err != nil {
+		return nil, categoryReferenceError(err, "parent_id")
+	}
+	return category, nil
+}
+
+// categoryReferenceError reports an unknown category referenced from a
+// request body as a validation error rather than a missing resource.
+func categoryReferenceError(err error, field string) error {
+	if errors.Is(err, domain.ErrCategoryNotFound) {
+		verr := &domain.ValidationError{}
+		verr.Add(field, "unknown category")
+		return verr
+	}
+	return err
+}
+
+func resolveCategory(categories []*domain.Category, ref string) (int64, error) {
+	id, idErr := strconv.ParseInt(ref, 10, 64)
+	for _, c := range categories {
+		if (idErr == nil && c.ID == id) || c.Slug == ref {
+			return c.ID, nil
+		}
+	}
+	return 0, domain.ErrCategoryNotFound
+}
+
+// buildCategoryFacets rolls product counts up the category tree, so that
+// a parent category counts the products of all its subcategories.
+func buildCategoryFacets(categories []*domain.Category, counts map[int64]int) []domain.CategoryFacet {
+	parents := make(map[int64]*int64, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+
+	totals := make(map[int64]int, len(categories))
+	for id, count := range counts {
+		// Ограничиваем глубину обхода на случай цикла в иерархии
+		for cur, depth := &id, 0; cur != nil && depth <= len(categories); cur, depth = parents[*cur], depth+1 {
+			totals[*cur] += count
+		}
+	}
+
+	facets := make([]domain.CategoryFacet, len(categories))
+	for i, c := range categories {
+		facets[i] = domain.CategoryFacet{
+			CategoryID: c.ID,
+			ParentID:   c.ParentID,
+			Name:       c.Name,
+			Slug:       c.Slug,
+			Count:      totals[c.ID],
+		}
+	}
+	return facets
+}
+
+// GetProduct returns a product of the catalog.
+func (s *Service) GetProduct(ctx context.Context, id int64) (*domain.Product, error) {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, domain.ErrProductNotFound
+	}
+	return product, nil
+}
+
+// CreateProduct adds a product to the catalog.
+func (s *Service) CreateProduct(ctx context.Context, req *ProductRequest) (*domain.Product, error) {
+	product := &domain.Product{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Currency:    req.Currency,
+		CategoryID:  req.CategoryID,
+	}
+	if req.StockQuantity != nil {
+		product.StockQuantity = *req.StockQuantity
+	}
+	if err := validateProduct(product); err != nil {
+		return nil, err
+	}
+	if err := s.productRepo.Create(ctx, product); err != nil {
+		return nil, categoryReferenceError(err, "category_id")
+	}
+	return product, nil
+}
+
+// ReplaceProduct overwrites all editable fields of a product. The stock is
+// only set if the request has it.
+func (s *Service) ReplaceProduct(ctx context.Context, id int64, req *ProductRequest) (*domain.Product, error) {
+	return s.updateProduct(ctx, id, &domain.ProductUpdate{
+		Name:          &req.Name,
+		Description:   &req.Description,
+		Price:         &req.Price,
+		Currency:      &req.Currency,
+		CategoryID:    req.CategoryID,
+		ClearCategory: req.CategoryID == nil,
+		StockQuantity: req.StockQuantity,
+	})
+}
+
+// PatchProduct updates the given fields of a product.
+func (s *Service) PatchProduct(ctx context.Context, id int64, patch *ProductPatch) (*domain.Product, error) {
+	return s.updateProduct(ctx, id, &domain.ProductUpdate{
+		Name:            patch.Name,
+		Description:     patch.Description,
+		Price:           patch.Price,
+		Currency:        patch.Currency,
+		CategoryID:      patch.CategoryID,
+		StockQuantity:   patch.StockQuantity,
+		StockAdjustment: patch.StockAdjustment,
+	})
+}
+
+// updateProduct applies the update in the repository without reading the
+// product first, so that it cannot overwrite stock sold in the meantime.
+func (s *Service) updateProduct(ctx context.Context, id int64, update *domain.ProductUpdate) (*domain.Product, error) {
+	if err := validateProductUpdate(update); err != nil {
+		return nil, err
+	}
+	product, err := s.productRepo.Update(ctx, id, update)
+	if err != nil {
+		return nil, categoryReferenceError(err, "category_id")
+	}
+	return product, nil
+}
+
+// DeleteProduct removes a product from the catalog. Existing orders keep their items.
+func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
+	return s.productRepo.Delete(ctx, id)
+}
+
+func validateProduct(p *domain.Product) error {
+	return validateProductUpdate(&domain.ProductUpdate{
+		Name:          &p.Name,
+		Price:         &p.Price,
+		Currency:      &p.Currency,
+		StockQuantity: &p.StockQuantity,
+	})
+}
+
+// validateProductUpdate checks the fields set in the update and normalizes its currency.
+func validateProductUpdate(u *domain.ProductUpdate) error {
+	verr := &domain.ValidationError{}
+	if u.Name != nil {
+		switch n := utf8.RuneCountInString(*u.Name); {
+		case n == 0:
+			verr.Add("name", "is required")
+		case n > 255:
+			verr.Add("name", "must be at most 255 characters")
+		}
+	}
+	if u.Price != nil {
+		if *u.Price <= 0 {
+			verr.Add("price", "must be greater than zero")
+		} else if *u.Price > maxProductPrice {
+			verr.Add("price", "is too large")
+		}
+	}
+	if u.Currency != nil {
+		if *u.Currency == "" {
+			*u.Currency = money.DefaultCurrency
+		} else if currency, err := money.ParseCurrency(string(*u.Currency)); err != nil {
+			verr.Add("currency", "must be a three-letter ISO 4217 code")
+		} else {
+			*u.Currency = currency
+		}
+	}
+	if u.StockQuantity != nil && *u.StockQuantity < 0 {
+		verr.Add("stock_quantity", "must not be negative")
+	}
+	if u.StockQuantity != nil && u.StockAdjustment != nil {
+		verr.Add("stock_adjustment", "cannot be combined with stock_quantity")
+	}
+	return verr.OrNil()
+}
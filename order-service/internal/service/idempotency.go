@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// BeginIdempotentRequest reserves an Idempotency-Key for a request with the
+// given fingerprint. It returns nil when the caller should process the
+// request, or the stored record whose response must be replayed.
+func (s *Service) BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	record, created, err := s.idempotencyRepo.Reserve(ctx, key, fingerprint, s.cfg.IdempotencyKeyTTL)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return nil, nil
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if !record.Completed() {
+		return nil, domain.ErrIdempotencyKeyInProgress
+	}
+	return record, nil
+}
+
+// CompleteIdempotentRequest stores the response to replay for the key.
+func (s *Service) CompleteIdempotentRequest(ctx context.Context, key string, statusCode int, body []byte) error {
+	return s.idempotencyRepo.Complete(ctx, key, statusCode, body)
+}
+
+// ReleaseIdempotentRequest frees the key of a failed request so the client can retry it.
+func (s *Service) ReleaseIdempotentRequest(ctx context.Context, key string) error {
+	return s.idempotencyRepo.Release(ctx, key)
+}
+
+// PurgeExpiredIdempotencyKeys deletes keys older than the configured TTL.
+func (s *Service) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.idempotencyRepo.DeleteExpired(ctx)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const (
+	paymentTimeoutReason = "payment timeout"
+	expiryBatchSize      = 100
+)
+
+// ExpireUnpaidOrders cancels orders that have stayed NEW for longer than
+// the payment timeout and returns how many were cancelled.
+//
+// Each order is cancelled through ChangeStatus, which locks the order row and
+// re-checks the status, so replicas running the sweep at the same time and a
+// FINISHED event arriving at the same moment cannot both win: whichever comes
+// second is rejected as an invalid transition. The cancellation asks payment
+// service to refund the order, so a payment made before it is processed is
+// returned, and one attempted after it is not charged.
+func (s *Service) ExpireUnpaidOrders(ctx context.Context) (int, error) {
+	if s.cfg.PaymentTimeout <= 0 {
+		return 0, nil
+	}
+
+	expired := 0
+	for {
+		orders, err := s.orderRepo.GetStale(ctx, domain.StatusNew, s.cfg.PaymentTimeout, expiryBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, order := range orders {
+			outboxMsg, err := cancelRequestedMessage(order, paymentTimeoutReason)
+			if err != nil {
+				return expired, err
+			}
+			change := domain.StatusChange{
+				OrderID: order.ID,
+				To:      domain.StatusCancelled,
+				Reason:  paymentTimeoutReason,
+				Source:  domain.SourceSystem,
+			}
+			err = s.orderRepo.ChangeStatus(ctx, change, outboxMsg)
+			// Заказ уже оплачен или отменён другим экземпляром
+			if errors.Is(err, domain.ErrInvalidStatusTransition) || errors.Is(err, domain.ErrOrderNotFound) {
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			log.Printf("Order %s cancelled: %s", order.ID, paymentTimeoutReason)
+			expired++
+			s.statusFeed.Notify()
+		}
+
+		if len(orders) < expiryBatchSize {
+			return expired, nil
+		}
+	}
+}
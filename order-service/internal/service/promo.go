@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+const maxPromoCodeLength = 64
+
+// ListPromoCodes returns all promo codes.
+func (s *Service) ListPromoCodes(ctx context.Context) ([]*domain.PromoCode, error) {
+	promos, err := s.promoRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if promos == nil {
+		promos = []*domain.PromoCode{}
+	}
+	return promos, nil
+}
+
+// CreatePromoCode validates and stores a new promo code. Codes are case-insensitive.
+func (s *Service) CreatePromoCode(ctx context.Context, req *PromoCodeRequest) (*domain.PromoCode, error) {
+	verr := &domain.ValidationError{}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if code == "" || len(code) > maxPromoCodeLength || strings.ContainsAny(code, " \t\n") {
+		verr.Add("code", fmt.Sprintf("must be 1-%d characters without spaces", maxPromoCodeLength))
+	}
+
+	promo := &domain.PromoCode{
+		Code:                  code,
+		Description:           req.Description,
+		Type:                  req.Type,
+		MinOrderAmount:        req.MinOrderAmount,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		StartsAt:              req.StartsAt,
+		EndsAt:                req.EndsAt,
+		Active:                req.Active == nil || *req.Active,
+		ProductIDs:            req.ProductIDs,
+		CategoryIDs:           req.CategoryIDs,
+	}
+
+	switch req.Type {
+	case domain.DiscountPercentage:
+		if req.PercentOff == nil || *req.PercentOff < 1 || *req.PercentOff > 100 {
+			verr.Add("percent_off", "must be between 1 and 100")
+		}
+		if req.AmountOff != nil {
+			verr.Add("amount_off", "not allowed for percentage codes")
+		}
+		promo.PercentOff = req.PercentOff
+	case domain.DiscountFixed:
+		if req.AmountOff == nil || !req.AmountOff.IsPositive() {
+			verr.Add("amount_off", "must be positive")
+		}
+		if req.PercentOff != nil {
+			verr.Add("percent_off", "not allowed for fixed codes")
+		}
+		if req.Currency == nil {
+			verr.Add("currency", "is required for fixed codes")
+		}
+		promo.AmountOff = req.AmountOff
+	default:
+		verr.Add("discount_type", fmt.Sprintf("must be %q or %q", domain.DiscountPercentage, domain.DiscountFixed))
+	}
+
+	if req.Currency != nil {
+		currency, err := money.ParseCurrency(string(*req.Currency))
+		if err != nil {
+			verr.Add("currency", "must be a three-letter ISO 4217 code")
+		} else {
+			promo.Currency = &currency
+		}
+	}
+	if req.MinOrderAmount != nil {
+		if !req.MinOrderAmount.IsPositive() {
+			verr.Add("min_order_amount", "must be positive")
+		}
+		if req.Currency == nil {
+			verr.Add("currency", "is required with min_order_amount")
+		}
+	}
+	if req.MaxRedemptions != nil && *req.MaxRedemptions <= 0 {
+		verr.Add("max_redemptions", "must be positive")
+	}
+	if req.MaxRedemptionsPerUser != nil && *req.MaxRedemptionsPerUser <= 0 {
+		verr.Add("max_redemptions_per_user", "must be positive")
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		verr.Add("ends_at", "must be after starts_at")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+	if promo.ProductIDs == nil {
+		promo.ProductIDs = []int64{}
+	}
+	if promo.CategoryIDs == nil {
+		promo.CategoryIDs = []int64{}
+	}
+
+	if err := s.promoRepo.Create(ctx, promo); err != nil {
+		if errors.Is(err, domain.ErrProductNotFound) {
+			verr := &domain.ValidationError{}
+			verr.Add("product_ids", "unknown product")
+			return nil, verr
+		}
+		return nil, categoryReferenceError(err, "category_ids")
+	}
+	return promo, nil
+}
+
+// applyPromoCode checks that the code can be applied to the order and
+// computes its discount. The usage limits are checked here to give a clear
+// error early, and once more atomically when the order is stored.
+func (s *Service) applyPromoCode(ctx context.Context, order *domain.Order, products map[int64]*domain.Product, code string) (*domain.OrderDiscount, error) {
+	reject := func(msg string) error {
+		verr := &domain.ValidationError{}
+		verr.Add("promo_code", msg)
+		return verr
+	}
+
+	promo, err := s.promoRepo.GetByCode(ctx, strings.TrimSpace(code))
+	if errors.Is(err, domain.ErrPromoCodeNotFound) {
+		return nil, reject("unknown promo code")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo code: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case !promo.Active:
+		return nil, reject("promo code is not active")
+	case promo.StartsAt != nil && now.Before(*promo.StartsAt):
+		return nil, reject("promo code is not valid yet")
+	case promo.EndsAt != nil && !now.Before(*promo.EndsAt):
+		return nil, reject("promo code has expired")
+	case promo.Currency != nil && *promo.Currency != order.Currency:
+		return nil, reject(fmt.Sprintf("promo code only applies to orders in %s", *promo.Currency))
+	case promo.MinOrderAmount != nil && order.SubtotalAmount < *promo.MinOrderAmount:
+		return nil, reject(fmt.Sprintf("order total must be at least %s %s", promo.MinOrderAmount, order.Currency))
+	case promo.MaxRedemptions != nil && promo.RedemptionsCount >= *promo.MaxRedemptions:
+		return nil, reject("promo code usage limit reached")
+	}
+	if promo.MaxRedemptionsPerUser != nil {
+		used, err := s.promoRepo.CountUserRedemptions(ctx, promo.ID, order.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count promo code redemptions: %w", err)
+		}
+		if used >= *promo.MaxRedemptionsPerUser {
+			return nil, reject("promo code has already been used")
+		}
+	}
+
+	eligible := order.SubtotalAmount
+	if promo.Restricted() {
+		eligible, err = s.eligibleAmount(ctx, promo, order, products)
+		if err != nil {
+			return nil, err
+		}
+		if eligible == 0 {
+			return nil, reject("promo code does not apply to any item of the order")
+		}
+	}
+
+	var amount money.Amount
+	switch promo.Type {
+	case domain.DiscountPercentage:
+		amount, err = eligible.Percent(int64(*promo.PercentOff))
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate discount: %w", err)
+		}
+	case domain.DiscountFixed:
+		amount = *promo.AmountOff
+		if amount > eligible {
+			amount = eligible
+		}
+	}
+	if !amount.IsPositive() {
+		return nil, reject("promo code gives no discount for this order")
+	}
+
+	description := promo.Description
+	if description == "" {
+		description = "Promo code " + promo.Code
+	}
+	return &domain.OrderDiscount{
+		OrderID:     order.ID,
+		PromoCodeID: &promo.ID,
+		Code:        promo.Code,
+		Description: description,
+		Amount:      amount,
+	}, nil
+}
+
+// eligibleAmount sums the order lines the promo code is restricted to.
+func (s *Service) eligibleAmount(ctx context.Context, promo *domain.PromoCode, order *domain.Order, products map[int64]*domain.Product) (money.Amount, error) {
+	productIDs := make(map[int64]bool, len(promo.ProductIDs))
+	for _, id := range promo.ProductIDs {
+		productIDs[id] = true
+	}
+
+	var parents map[int64]*int64
+	if len(promo.CategoryIDs) > 0 {
+		categories, err := s.productRepo.GetCategories(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get categories: %w", err)
+		}
+		parents = make(map[int64]*int64, len(categories))
+		for _, c := range categories {
+			parents[c.ID] = c.ParentID
+		}
+	}
+	categoryIDs := make(map[int64]bool, len(promo.CategoryIDs))
+	for _, id := range promo.CategoryIDs {
+		categoryIDs[id] = true
+	}
+	// Товар подходит, если промокод распространяется на его категорию или любую из родительских
+	inCategory := func(id *int64) bool {
+		for depth := 0; id != nil && depth <= len(parents); id, depth = parents[*id], depth+1 {
+			if categoryIDs[*id] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var eligible money.Amount
+	for _, item := range order.Items {
+		product := products[item.ProductID]
+		if !productIDs[item.ProductID] && !inCategory(product.CategoryID) {
+			continue
+		}
+		lineTotal, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return 0, fmt.Errorf("failed to calculate discount: %w", err)
+		}
+		eligible += lineTotal
+	}
+	return eligible, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// Reorder places a new order for the user of an earlier order with the same
+// products and quantities at the current prices.
+//
+// Products that were deleted or are out of stock are skipped, and quantities
+// are reduced to what is in stock; every such change is reported on the item.
+// The new order goes through the same pricing, reservation and outbox flow
+// as any other order.
+func (s *Service) Reorder(ctx context.Context, orderID uuid.UUID, req *ReorderRequest) (*domain.Reorder, error) {
+	original, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	// Один товар может встречаться в заказе несколькими строками
+	var items []*domain.ReorderItem
+	byProduct := make(map[int64]*domain.ReorderItem)
+	for _, item := range original.Items {
+		if ri, ok := byProduct[item.ProductID]; ok {
+			ri.OriginalQuantity += item.Quantity
+			continue
+		}
+		ri := &domain.ReorderItem{
+			ProductID:        item.ProductID,
+			ProductName:      item.ProductName,
+			OriginalQuantity: item.Quantity,
+			OriginalPrice:    item.Price,
+		}
+		byProduct[item.ProductID] = ri
+		items = append(items, ri)
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrNothingToReorder
+	}
+
+	productIDs := make([]int64, len(items))
+	for i, ri := range items {
+		productIDs[i] = ri.ProductID
+	}
+	products, err := s.productRepo.GetProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	productsMap := make(map[int64]*domain.Product, len(products))
+	for _, p := range products {
+		productsMap[p.ID] = p
+	}
+
+	create := &CreateOrderRequest{
+		UserID:          original.UserID,
+		PromoCode:       req.PromoCode,
+		ShippingAddress: req.ShippingAddress,
+	}
+	if create.ShippingAddress == nil && original.ShippingAddress != nil {
+		address := *original.ShippingAddress
+		create.ShippingAddress = &address
+	}
+
+	var problems []string
+	for _, ri := range items {
+		product := productsMap[ri.ProductID]
+		switch {
+		case product == nil:
+			ri.Problem = "product is no longer available"
+		case product.Currency != original.Currency:
+			ri.Problem = "product is now priced in " + string(product.Currency)
+		case product.AvailableQuantity() <= 0:
+			ri.Problem = "out of stock"
+		}
+		if ri.Problem != "" {
+			problems = append(problems, ri.ProductName+": "+ri.Problem)
+			continue
+		}
+
+		ri.ProductName = product.Name
+		ri.Quantity = min(ri.OriginalQuantity, product.AvailableQuantity())
+		if ri.Quantity < ri.OriginalQuantity {
+			ri.Problem = fmt.Sprintf("only %d in stock", ri.Quantity)
+		}
+		ri.CurrentPrice = product.Price
+		ri.PriceDifference = product.Price - ri.OriginalPrice
+		create.Items = append(create.Items, CreateOrderItem{ProductID: ri.ProductID, Quantity: ri.Quantity})
+	}
+	if len(create.Items) == 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrNothingToReorder, strings.Join(problems, "; "))
+	}
+
+	order, err := s.CreateOrder(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.Reorder{
+		OriginalOrderID: original.ID,
+		Order:           order,
+		Items:           make([]domain.ReorderItem, len(items)),
+	}
+	for i, ri := range items {
+		diff, err := ri.PriceDifference.Mul(int64(ri.Quantity))
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate price difference: %w", err)
+		}
+		result.PriceDifference += diff
+		result.Items[i] = *ri
+	}
+	return result, nil
+}
@@ -1,11 +1,132 @@
 package service
 
+import (
+	"time"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
 type CreateOrderRequest struct {
-	UserID string            `json:"user_id"`
-	Items  []CreateOrderItem `json:"items"`
+	UserID          string            `json:"user_id"`
+	Items           []CreateOrderItem `json:"items"`
+	PromoCode       string            `json:"promo_code"`
+	ShippingAddress *domain.Address   `json:"shipping_address"`
 }
 
 type CreateOrderItem struct {
 	ProductID int64 `json:"product_id"`
 	Quantity  int   `json:"quantity"`
 }
+
+// ProductRequest is the full set of editable product fields, used by create
+// and replace. A replace without stock_quantity keeps the current stock.
+type ProductRequest struct {
+	Name          string         `json:"name"`
+	Description   string         `json:"description"`
+	Price         money.Amount   `json:"price"`
+	Currency      money.Currency `json:"currency"`
+	CategoryID    *int64         `json:"category_id"`
+	StockQuantity *int           `json:"stock_quantity"`
+}
+
+// ProductPatch is a partial product update; nil fields are left unchanged.
+type ProductPatch struct {
+	Name            *string         `json:"name"`
+	Description     *string         `json:"description"`
+	Price           *money.Amount   `json:"price"`
+	Currency        *money.Currency `json:"currency"`
+	CategoryID      *int64          `json:"category_id"`
+	StockQuantity   *int            `json:"stock_quantity"`
+	StockAdjustment *int            `json:"stock_adjustment"`
+}
+
+// ReturnRequest lists the order items being returned.
+type ReturnRequest struct {
+	Items  []ReturnItemRequest `json:"items"`
+	Reason string              `json:"reason"`
+}
+
+type ReturnItemRequest struct {
+	ItemID   int64 `json:"item_id"`
+	Quantity int   `json:"quantity"`
+}
+
+// CartItemRequest sets the quantity of a product in a cart.
+type CartItemRequest struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+}
+
+// CheckoutRequest is the optional body of a cart checkout.
+type CheckoutRequest struct {
+	PromoCode       string          `json:"promo_code"`
+	ShippingAddress *domain.Address `json:"shipping_address"`
+}
+
+// PromoCodeRequest creates a promo code. PercentOff is required for
+// percentage codes, AmountOff and Currency for fixed ones.
+type PromoCodeRequest struct {
+	Code                  string              `json:"code"`
+	Description           string              `json:"description"`
+	Type                  domain.DiscountType `json:"discount_type"`
+	PercentOff            *int                `json:"percent_off"`
+	AmountOff             *money.Amount       `json:"amount_off"`
+	Currency              *money.Currency     `json:"currency"`
+	MinOrderAmount        *money.Amount       `json:"min_order_amount"`
+	MaxRedemptions        *int                `json:"max_redemptions"`
+	MaxRedemptionsPerUser *int                `json:"max_redemptions_per_user"`
+	StartsAt              *time.Time          `json:"starts_at"`
+	EndsAt                *time.Time          `json:"ends_at"`
+	Active                *bool               `json:"active"`
+	ProductIDs            []int64             `json:"product_ids"`
+	CategoryIDs           []int64             `json:"category_ids"`
+}
+
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+// WebhookSubscriptionRequest registers a webhook endpoint.
+type WebhookSubscriptionRequest struct {
+	URL         string                    `json:"url"`
+	EventTypes  []domain.WebhookEventType `json:"event_types"`
+	Description string                    `json:"description"`
+}
+
+// ShipmentRequest packs order items into a shipment. Without items,
+// everything not yet shipped is packed.
+type ShipmentRequest struct {
+	Items   []ShipmentItemRequest `json:"items"`
+	Carrier string                `json:"carrier"`
+}
+
+type ShipmentItemRequest struct {
+	ItemID   int64 `json:"item_id"`
+	Quantity int   `json:"quantity"`
+}
+
+// ShipRequest hands a shipment over to the carrier.
+type ShipRequest struct {
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// TaxRateRequest adds a tax rate. Rate is a decimal fraction such as "0.2";
+// without CategoryID the rate covers all categories, without Region the
+// whole country.
+type TaxRateRequest struct {
+	CategoryID *int64 `json:"category_id"`
+	Country    string `json:"country"`
+	Region     string `json:"region"`
+	Rate       string `json:"rate"`
+}
+
+// ReorderRequest is the optional body of a reorder. Without a shipping
+// address the address of the original order is used.
+type ReorderRequest struct {
+	PromoCode       string          `json:"promo_code"`
+	ShippingAddress *domain.Address `json:"shipping_address"`
+}
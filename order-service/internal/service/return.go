@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
+)
+
+// CreateReturn registers a return of some items of a FINISHED order.
+// The refund is issued once the return is approved.
+func (s *Service) CreateReturn(ctx context.Context, orderID uuid.UUID, req *ReturnRequest) (*domain.OrderReturn, error) {
+	ret := &domain.OrderReturn{
+		ID:      uuid.New(),
+		OrderID: orderID,
+		Status:  domain.ReturnRequested,
+		Reason:  req.Reason,
+		Items:   make([]domain.ReturnItem, len(req.Items)),
+	}
+	for i, item := range req.Items {
+		ret.Items[i] = domain.ReturnItem{ReturnID: ret.ID, OrderItemID: item.ItemID, Quantity: item.Quantity}
+	}
+
+	if err := s.returnRepo.Create(ctx, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListReturns returns the returns of an order.
+func (s *Service) ListReturns(ctx context.Context, orderID uuid.UUID) ([]*domain.OrderReturn, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+	return s.returnRepo.GetByOrderID(ctx, orderID)
+}
+
+// GetReturn returns a return of the order.
+func (s *Service) GetReturn(ctx context.Context, orderID, returnID uuid.UUID) (*domain.OrderReturn, error) {
+	ret, err := s.returnRepo.GetByID(ctx, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.OrderID != orderID {
+		return nil, domain.ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+// ApproveReturn approves a requested return and asks payment service to
+// refund it via the outbox.
+func (s *Service) ApproveReturn(ctx context.Context, orderID, returnID uuid.UUID) (*domain.OrderReturn, error) {
+	ret, err := s.GetReturn(ctx, orderID, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := domain.OrderReturnApprovedEvent{
+		ReturnID: ret.ID,
+		OrderID:  ret.OrderID,
+		UserID:   ret.UserID,
+		Amount:   ret.RefundAmount,
+		Currency: ret.Currency,
+		Items:    make([]domain.ReturnItemEvent, len(ret.Items)),
+	}
+	for i, item := range ret.Items {
+		event.Items[i] = domain.ReturnItemEvent{
+			OrderItemID: item.OrderItemID,
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			Amount:      item.RefundAmount,
+		}
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	outboxMsg := &outbox.Message{
+		ID:            uuid.New(),
+		Type:          "order_return_approved",
+		AggregateType: outbox.AggregateOrder,
+		AggregateID:   ret.OrderID.String(),
+		Payload:       payload,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	if err := s.returnRepo.ChangeStatus(ctx, ret.ID, domain.ReturnApproved, outboxMsg); err != nil {
+		return nil, err
+	}
+	return s.returnRepo.GetByID(ctx, ret.ID)
+}
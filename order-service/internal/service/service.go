@@ -3,37 +3,96 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
 	"github.com/mnntn/ecommerce-project/order-service/internal/kafka"
-	"github.com/mnntn/ecommerce-project/order-service/internal/outbox"
 	"github.com/mnntn/ecommerce-project/order-service/internal/repository"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
+const defaultCancelReason = "Cancelled by customer"
+
+// Config holds tunable business rules of the order service.
+type Config struct {
+	// CancelGracePeriod is how long after payment a FINISHED order can still be cancelled.
+	CancelGracePeriod time.Duration
+	// PaymentTimeout is how long an order may stay NEW before it is cancelled
+	// as unpaid. Zero disables the expiry.
+	PaymentTimeout time.Duration
+	// IdempotencyKeyTTL is how long a stored Idempotency-Key is replayed before it expires.
+	IdempotencyKeyTTL time.Duration
+	// TaxInclusive means product prices already include tax, so tax is not
+	// added on top of the order total.
+	TaxInclusive bool
+	// DefaultTaxCountry is the destination country taxed for orders without
+	// a shipping address. Empty means such orders are not taxed.
+	DefaultTaxCountry string
+	// AllowInsecureWebhooks accepts http webhook URLs and URLs of hosts in
+	// private networks. It is meant for local development only.
+	AllowInsecureWebhooks bool
+}
+
 // Service encapsulates all business logic for the order service.
 type Service struct {
-	orderRepo   repository.OrderRepository
-	productRepo repository.ProductRepository
-	producer    *kafka.Producer
-	outboxRepo  outbox.OutboxRepository
+	orderRepo       repository.OrderRepository
+	productRepo     repository.ProductRepository
+	producer        *kafka.Producer
+	outboxRepo      repository.OutboxRepository
+	idempotencyRepo repository.IdempotencyRepository
+	cartRepo        repository.CartRepository
+	promoRepo       repository.PromoRepository
+	returnRepo      repository.ReturnRepository
+	shipmentRepo    repository.ShipmentRepository
+	webhookRepo     repository.WebhookRepository
+	taxRepo         repository.TaxRepository
+	statusFeed      *StatusFeed
+	cfg             Config
 }
 
-func New(orderRepo repository.OrderRepository, productRepo repository.ProductRepository, producer *kafka.Producer, outboxRepo outbox.OutboxRepository) *Service {
+func New(orderRepo repository.OrderRepository, productRepo repository.ProductRepository, producer *kafka.Producer, outboxRepo repository.OutboxRepository, idempotencyRepo repository.IdempotencyRepository, cartRepo repository.CartRepository, promoRepo repository.PromoRepository, returnRepo repository.ReturnRepository, shipmentRepo repository.ShipmentRepository, webhookRepo repository.WebhookRepository, taxRepo repository.TaxRepository, statusFeed *StatusFeed, cfg Config) *Service {
 	return &Service{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
-		producer:    producer,
-		outboxRepo:  outboxRepo,
+		orderRepo:       orderRepo,
+		productRepo:     productRepo,
+		producer:        producer,
+		outboxRepo:      outboxRepo,
+		idempotencyRepo: idempotencyRepo,
+		cartRepo:        cartRepo,
+		promoRepo:       promoRepo,
+		returnRepo:      returnRepo,
+		shipmentRepo:    shipmentRepo,
+		webhookRepo:     webhookRepo,
+		taxRepo:         taxRepo,
+		statusFeed:      statusFeed,
+		cfg:             cfg,
 	}
 }
 
 // CreateOrder handles the business logic of creating a new order.
 func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*domain.Order, error) {
+	order, outboxMsg, err := s.prepareOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сохраняем заказ и outbox сообщение в одной транзакции
+	if err := s.orderRepo.CreateWithOutbox(ctx, order, outboxMsg); err != nil {
+		return nil, fmt.Errorf("failed to create order and outbox message in db: %w", err)
+	}
+
+	return order, nil
+}
+
+// prepareOrder prices the requested items at the current product prices and
+// builds the order together with its order_created outbox message.
+func (s *Service) prepareOrder(ctx context.Context, req *CreateOrderRequest) (*domain.Order, *outbox.Message, error) {
 	if len(req.Items) == 0 {
-		return nil, fmt.Errorf("order must contain at least one item")
+		verr := &domain.ValidationError{}
+		verr.Add("items", "order must contain at least one item")
+		return nil, nil, verr
 	}
 
 	productIDs := make([]int64, len(req.Items))
@@ -43,11 +102,7 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*do
 
 	products, err := s.productRepo.GetProductsByIDs(ctx, productIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get products: %w", err)
-	}
-
-	if len(products) != len(productIDs) {
-		return nil, fmt.Errorf("one or more products not found")
+		return nil, nil, fmt.Errorf("failed to get products: %w", err)
 	}
 
 	productsMap := make(map[int64]*domain.Product)
@@ -55,6 +110,23 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*do
 		productsMap[p.ID] = p
 	}
 
+	verr := &domain.ValidationError{}
+	for _, item := range req.Items {
+		if productsMap[item.ProductID] == nil {
+			verr.Add("items", fmt.Sprintf("product %d not found", item.ProductID))
+		}
+		if item.Quantity <= 0 || item.Quantity > domain.MaxOrderItemQuantity {
+			verr.Add("items", fmt.Sprintf("quantity of product %d must be between 1 and %d", item.ProductID, domain.MaxOrderItemQuantity))
+		}
+	}
+	if req.ShippingAddress != nil {
+		req.ShippingAddress.Normalize()
+		req.ShippingAddress.Validate(verr, "shipping_address")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, nil, err
+	}
+
 	// Создаем описание заказа
 	description := "Order with items: "
 	for i, item := range req.Items {
@@ -66,74 +138,195 @@ func (s *Service) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*do
 	}
 
 	order := &domain.Order{
-		ID:          uuid.New(),
-		UserID:      req.UserID,
-		Status:      domain.StatusNew,
-		Description: description,
-		Items:       make([]domain.OrderItem, len(req.Items)),
-		TotalAmount: 0,
+		ID:              uuid.New(),
+		UserID:          req.UserID,
+		Status:          domain.StatusNew,
+		Description:     description,
+		ShippingAddress: req.ShippingAddress,
+		Items:           make([]domain.OrderItem, len(req.Items)),
+		Discounts:       []domain.OrderDiscount{},
+		Currency:        products[0].Currency,
 	}
 
 	for i, item := range req.Items {
 		product := productsMap[item.ProductID]
-		itemTotal := product.Price * float64(item.Quantity)
-		order.TotalAmount += itemTotal
+		if product.Currency != order.Currency {
+			verr := &domain.ValidationError{}
+			verr.Add("items", "all products of an order must be priced in the same currency")
+			return nil, nil, verr
+		}
+		itemTotal, err := product.Price.Mul(int64(item.Quantity))
+		if err == nil {
+			order.SubtotalAmount, err = order.SubtotalAmount.Add(itemTotal)
+		}
+		if err != nil {
+			// Mul и Add ошибаются только при переполнении
+			verr := &domain.ValidationError{}
+			verr.Add("items", "order total is out of range")
+			return nil, nil, verr
+		}
 		order.Items[i] = domain.OrderItem{
-			ProductID: product.ID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
+			OrderID:     order.ID,
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Quantity:    item.Quantity,
+			Price:       product.Price,
 		}
 	}
 
+	if req.PromoCode != "" {
+		discount, err := s.applyPromoCode(ctx, order, productsMap, req.PromoCode)
+		if err != nil {
+			return nil, nil, err
+		}
+		order.Discounts = append(order.Discounts, *discount)
+		order.DiscountAmount += discount.Amount
+	}
+	if err := s.applyTaxes(ctx, order, productsMap); err != nil {
+		return nil, nil, err
+	}
+	order.TotalAmount = orderTotal(order)
+
 	// Формируем outbox сообщение
 	outboxEvent := domain.OrderCreatedEvent{
-		OrderID:     order.ID,
-		UserID:      order.UserID,
-		TotalAmount: order.TotalAmount,
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		SubtotalAmount: order.SubtotalAmount,
+		DiscountAmount: order.DiscountAmount,
+		TaxAmount:      order.TaxAmount,
+		TaxInclusive:   order.TaxInclusive,
+		TotalAmount:    order.TotalAmount,
+		Currency:       order.Currency,
 	}
 	payload, err := json.Marshal(outboxEvent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal outbox event: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal outbox event: %w", err)
 	}
-	outboxMsg := &outbox.OutboxMessage{
-		ID:        uuid.New(),
-		Type:      "order_created",
-		Payload:   payload,
-		Status:    "pending",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	outboxMsg := &outbox.Message{
+		ID:            uuid.New(),
+		Type:          "order_created",
+		AggregateType: outbox.AggregateOrder,
+		AggregateID:   order.ID.String(),
+		Payload:       payload,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
-	// Сохраняем заказ и outbox сообщение в одной транзакции
-	if err := s.orderRepo.CreateWithOutbox(ctx, order, outboxMsg); err != nil {
-		return nil, fmt.Errorf("failed to create order and outbox message in db: %w", err)
+	return order, outboxMsg, nil
+}
+
+// CancelOrder cancels a NEW order or a FINISHED order within the grace period
+// and asks payment service to refund it via the outbox.
+func (s *Service) CancelOrder(ctx context.Context, id uuid.UUID, reason string) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+	if reason == "" {
+		reason = defaultCancelReason
 	}
 
-	return order, nil
+	outboxMsg, err := cancelRequestedMessage(order, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	change := domain.StatusChange{
+		OrderID: order.ID,
+		To:      domain.StatusCancelled,
+		Reason:  reason,
+		Source:  domain.SourceCustomer,
+		// Отмена возвращает всю списанную сумму, поэтому после возврата части
+		// товаров или отгрузки заказ можно только вернуть, но не отменить
+		RejectIfFulfilling: true,
+	}
+	if order.Status == domain.StatusFinished {
+		change.MaxStatusAge = s.cfg.CancelGracePeriod
+	}
+	if err := s.orderRepo.ChangeStatus(ctx, change, outboxMsg); err != nil {
+		if errors.Is(err, domain.ErrInvalidStatusTransition) || errors.Is(err, domain.ErrStatusWindowExpired) {
+			return nil, fmt.Errorf("%w: %v", domain.ErrOrderNotCancellable, err)
+		}
+		return nil, err
+	}
+	s.statusFeed.Notify()
+
+	return s.orderRepo.GetByID(ctx, order.ID)
 }
 
-// ListProducts returns all available products.
-func (s *Service) ListProducts(ctx context.Context) ([]*domain.Product, error) {
-	return s.productRepo.GetAll(ctx)
+// cancelRequestedMessage builds the order_cancel_requested outbox message that
+// makes payment service refund the order, or not charge it if it is not paid yet.
+func cancelRequestedMessage(order *domain.Order, reason string) (*outbox.Message, error) {
+	payload, err := json.Marshal(domain.OrderCancelRequestedEvent{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Reason:  reason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	return &outbox.Message{
+		ID:            uuid.New(),
+		Type:          "order_cancel_requested",
+		AggregateType: outbox.AggregateOrder,
+		AggregateID:   order.ID.String(),
+		Payload:       payload,
+		Status:        "pending",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}, nil
 }
 
 // GetOrderByID retrieves an order by its ID.
 func (s *Service) GetOrderByID(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
-	// This should also retrieve order items. We'll update the repo method later.
 	return s.orderRepo.GetByID(ctx, id)
 }
 
-// GetUserOrders retrieves all orders for a specific user.
-func (s *Service) GetUserOrders(ctx context.Context, userID string) ([]*domain.Order, error) {
-	return s.orderRepo.GetByUserID(ctx, userID)
-}
+// ListOrders returns a page of orders matching the filter together with
+// the cursor of the next page, if there is one.
+func (s *Service) ListOrders(ctx context.Context, filter domain.OrderFilter) (*domain.OrderPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultOrderPageSize
+	}
+	if filter.Limit > domain.MaxOrderPageSize {
+		filter.Limit = domain.MaxOrderPageSize
+	}
+	if filter.Sort == "" {
+		filter.Sort = domain.SortCreatedAtDesc
+	}
 
-// GetAllOrders retrieves all orders.
-func (s *Service) GetAllOrders(ctx context.Context) ([]*domain.Order, error) {
-	return s.orderRepo.GetAll(ctx)
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	pageSize := filter.Limit
+	filter.Limit++
+	orders, err := s.orderRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.OrderPage{Orders: orders}
+	if len(orders) > pageSize {
+		page.Orders = orders[:pageSize]
+		last := page.Orders[pageSize-1]
+		page.NextCursor = domain.NewOrderCursor(last, filter.Sort).Encode()
+	}
+	if page.Orders == nil {
+		page.Orders = []*domain.Order{}
+	}
+	return page, nil
 }
 
-// UpdateOrderStatus updates the status of an order. Used by Kafka consumer.
-func (s *Service) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status domain.OrderStatus) error {
-	return s.orderRepo.UpdateStatus(ctx, orderID, status)
+// GetOrderHistory returns the status timeline of an order.
+func (s *Service) GetOrderHistory(ctx context.Context, id uuid.UUID) ([]*domain.StatusHistoryEntry, error) {
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+	return s.orderRepo.GetStatusHistory(ctx, id)
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// CreateShipment packs items of a FINISHED order into a new shipment.
+func (s *Service) CreateShipment(ctx context.Context, orderID uuid.UUID, req *ShipmentRequest) (*domain.Shipment, error) {
+	shipment := &domain.Shipment{
+		ID:      uuid.New(),
+		OrderID: orderID,
+		Status:  domain.ShipmentPacked,
+		Carrier: strings.TrimSpace(req.Carrier),
+		Items:   make([]domain.ShipmentItem, len(req.Items)),
+	}
+	for i, item := range req.Items {
+		shipment.Items[i] = domain.ShipmentItem{ShipmentID: shipment.ID, OrderItemID: item.ItemID, Quantity: item.Quantity}
+	}
+
+	if err := s.shipmentRepo.Create(ctx, shipment); err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// ListShipments returns the shipments of an order.
+func (s *Service) ListShipments(ctx context.Context, orderID uuid.UUID) ([]*domain.Shipment, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+	return s.shipmentRepo.GetByOrderID(ctx, orderID)
+}
+
+// GetShipment returns a shipment of the order.
+func (s *Service) GetShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*domain.Shipment, error) {
+	shipment, err := s.shipmentRepo.GetByID(ctx, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.OrderID != orderID {
+		return nil, domain.ErrShipmentNotFound
+	}
+	return shipment, nil
+}
+
+// ShipShipment hands a packed shipment over to the carrier. The tracking
+// number is required; the carrier may have been set when it was packed.
+func (s *Service) ShipShipment(ctx context.Context, orderID, shipmentID uuid.UUID, req *ShipRequest) (*domain.Shipment, error) {
+	shipment, err := s.GetShipment(ctx, orderID, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	change := domain.ShipmentChange{
+		ShipmentID:     shipment.ID,
+		To:             domain.ShipmentShipped,
+		Carrier:        strings.TrimSpace(req.Carrier),
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+	}
+	verr := &domain.ValidationError{}
+	if change.TrackingNumber == "" {
+		verr.Add("tracking_number", "is required")
+	}
+	if change.Carrier == "" && shipment.Carrier == "" {
+		verr.Add("carrier", "is required")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	return s.changeShipmentStatus(ctx, change)
+}
+
+// DeliverShipment records that the shipment reached the customer.
+func (s *Service) DeliverShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*domain.Shipment, error) {
+	return s.advanceShipment(ctx, orderID, shipmentID, domain.ShipmentDelivered)
+}
+
+// ReturnShipment records that the carrier brought the shipment back undelivered.
+func (s *Service) ReturnShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*domain.Shipment, error) {
+	return s.advanceShipment(ctx, orderID, shipmentID, domain.ShipmentReturned)
+}
+
+func (s *Service) advanceShipment(ctx context.Context, orderID, shipmentID uuid.UUID, to domain.ShipmentStatus) (*domain.Shipment, error) {
+	shipment, err := s.GetShipment(ctx, orderID, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.changeShipmentStatus(ctx, domain.ShipmentChange{ShipmentID: shipment.ID, To: to})
+}
+
+func (s *Service) changeShipmentStatus(ctx context.Context, change domain.ShipmentChange) (*domain.Shipment, error) {
+	if err := s.shipmentRepo.ChangeStatus(ctx, change); err != nil {
+		return nil, err
+	}
+	return s.shipmentRepo.GetByID(ctx, change.ShipmentID)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// StatusChangeBatchSize bounds the number of status changes read at once by a stream.
+const StatusChangeBatchSize = 100
+
+// StatusFeed wakes up order status streams when a status change is applied.
+// It carries no data: streams read the changes from the status history, so
+// a missed or merged wake-up never loses an event.
+type StatusFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func NewStatusFeed() *StatusFeed {
+	return &StatusFeed{subscribers: make(map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel that receives a value after status changes
+// and a function that cancels the subscription.
+func (f *StatusFeed) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subscribers, ch)
+		f.mu.Unlock()
+	}
+}
+
+// Notify wakes up all subscribers without blocking.
+func (f *StatusFeed) Notify() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SubscribeStatusChanges subscribes to wake-ups of the order status feed.
+func (s *Service) SubscribeStatusChanges() (<-chan struct{}, func()) {
+	return s.statusFeed.Subscribe()
+}
+
+// GetUserStatusChanges returns the status changes of the user's orders
+// recorded after the change afterID, oldest first.
+func (s *Service) GetUserStatusChanges(ctx context.Context, userID string, afterID int64) ([]*domain.StatusHistoryEntry, error) {
+	return s.orderRepo.GetUserStatusChanges(ctx, userID, afterID, StatusChangeBatchSize)
+}
+
+// GetLastUserStatusChangeID returns the ID of the latest status change of the user's orders.
+func (s *Service) GetLastUserStatusChangeID(ctx context.Context, userID string) (int64, error) {
+	return s.orderRepo.GetLastUserStatusChangeID(ctx, userID)
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/google/uuid"
@@ -9,14 +12,60 @@ import (
 	"github.com/mnntn/ecommerce-project/order-service/internal/repository"
 )
 
+// Типы событий топика payments (заголовок "type" сообщения Kafka)
+const (
+	EventOrderStatusUpdated  = "order_status_updated"
+	EventOrderReturnRefunded = "order_return_refunded"
+)
+
 type StatusProcessor struct {
-	orderRepo repository.OrderRepository
+	orderRepo  repository.OrderRepository
+	returnRepo repository.ReturnRepository
+	statusFeed *StatusFeed
 }
 
-func NewStatusProcessor(orderRepo repository.OrderRepository) *StatusProcessor {
+func NewStatusProcessor(orderRepo repository.OrderRepository, returnRepo repository.ReturnRepository, statusFeed *StatusFeed) *StatusProcessor {
 	return &StatusProcessor{
-		orderRepo: orderRepo,
+		orderRepo:  orderRepo,
+		returnRepo: returnRepo,
+		statusFeed: statusFeed,
+	}
+}
+
+// ProcessPaymentEvent handles an event of the payments topic according to its type.
+func (p *StatusProcessor) ProcessPaymentEvent(ctx context.Context, eventType string, payload []byte) error {
+	switch eventType {
+	case EventOrderStatusUpdated:
+		var event domain.OrderStatusUpdatedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal OrderStatusUpdatedEvent: %w", err)
+		}
+		return p.ProcessOrderStatusUpdated(ctx, &event)
+	case EventOrderReturnRefunded:
+		var event domain.OrderReturnRefundedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal OrderReturnRefundedEvent: %w", err)
+		}
+		return p.ProcessOrderReturnRefunded(ctx, &event)
+	default:
+		log.Printf("Skipping payment event of unknown type: %s", eventType)
+		return nil
+	}
+}
+
+// ProcessOrderReturnRefunded marks a return refunded. Repeated events are ignored.
+func (p *StatusProcessor) ProcessOrderReturnRefunded(ctx context.Context, event *domain.OrderReturnRefundedEvent) error {
+	err := p.returnRepo.ChangeStatus(ctx, event.ReturnID, domain.ReturnRefunded)
+	if errors.Is(err, domain.ErrInvalidReturnTransition) || errors.Is(err, domain.ErrReturnNotFound) {
+		log.Printf("Rejected refund of return %s: %v", event.ReturnID, err)
+		return nil
+	}
+	if err != nil {
+		return err
 	}
+
+	log.Printf("Return %s of order %s refunded: %s %s", event.ReturnID, event.OrderID, event.Amount, event.Currency)
+	return nil
 }
 
 func (p *StatusProcessor) ProcessOrderStatusUpdated(ctx context.Context, event *domain.OrderStatusUpdatedEvent) error {
@@ -40,11 +89,22 @@ func (p *StatusProcessor) ProcessOrderStatusUpdated(ctx context.Context, event *
 		return nil
 	}
 
-	err = p.orderRepo.UpdateStatus(ctx, orderID, status)
-	if err != nil {
+	change := domain.StatusChange{
+		OrderID: orderID,
+		To:      status,
+		Reason:  event.Reason,
+		Source:  domain.SourcePayment,
+	}
+	if err := p.orderRepo.ChangeStatus(ctx, change); err != nil {
+		// Запоздавшие и повторные события не должны менять статус заказа
+		if errors.Is(err, domain.ErrInvalidStatusTransition) || errors.Is(err, domain.ErrOrderNotFound) {
+			log.Printf("Rejected status update for order %s: %v", orderID, err)
+			return nil
+		}
 		log.Printf("Failed to update order status: %v", err)
 		return err
 	}
+	p.statusFeed.Notify()
 
 	log.Printf("Successfully updated order %s status to %s", orderID, status)
 	return nil
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+// taxRatePattern matches a fraction below 1 with at most five decimals, as
+// stored in tax_rates.rate.
+var taxRatePattern = regexp.MustCompile(`^0(\.\d{1,5})?$`)
+
+// ListTaxRates returns all tax rates.
+func (s *Service) ListTaxRates(ctx context.Context) ([]*domain.TaxRate, error) {
+	return s.taxRepo.GetAll(ctx)
+}
+
+// CreateTaxRate validates and stores a tax rate.
+func (s *Service) CreateTaxRate(ctx context.Context, req *TaxRateRequest) (*domain.TaxRate, error) {
+	rate := &domain.TaxRate{
+		CategoryID: req.CategoryID,
+		Country:    strings.ToUpper(strings.TrimSpace(req.Country)),
+		Region:     strings.TrimSpace(req.Region),
+		Rate:       strings.TrimSpace(req.Rate),
+	}
+
+	verr := &domain.ValidationError{}
+	if len(rate.Country) != 2 || strings.Trim(rate.Country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		verr.Add("country", "must be a two-letter ISO 3166-1 code")
+	}
+	if !taxRatePattern.MatchString(rate.Rate) {
+		verr.Add("rate", "must be a decimal fraction from 0 to 0.99999, e.g. \"0.2\"")
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	if err := s.taxRepo.Create(ctx, rate); err != nil {
+		return nil, categoryReferenceError(err, "category_id")
+	}
+	return rate, nil
+}
+
+// DeleteTaxRate removes a tax rate. Orders already placed keep the tax they were charged.
+func (s *Service) DeleteTaxRate(ctx context.Context, id int64) error {
+	return s.taxRepo.Delete(ctx, id)
+}
+
+// applyTaxes computes the tax of every order line for the destination of the
+// order and adds it to the total unless prices include tax.
+//
+// The destination is the shipping address, or the default tax country for
+// orders without one. Tax is charged on what the customer pays for the line,
+// so an order-level discount is spread over the lines in proportion to their
+// totals first. Lines without an applicable rate are not taxed.
+func (s *Service) applyTaxes(ctx context.Context, order *domain.Order, products map[int64]*domain.Product) error {
+	order.TaxInclusive = s.cfg.TaxInclusive
+	for i := range order.Items {
+		order.Items[i].TaxRate = "0"
+	}
+
+	country, region := s.cfg.DefaultTaxCountry, ""
+	if order.ShippingAddress != nil {
+		country, region = order.ShippingAddress.Country, order.ShippingAddress.Region
+	}
+	if country == "" {
+		return nil
+	}
+
+	rates, err := s.taxRepo.GetByCountry(ctx, country)
+	if err != nil {
+		return fmt.Errorf("failed to get tax rates: %w", err)
+	}
+	if len(rates) == 0 {
+		return nil
+	}
+
+	categories, err := s.productRepo.GetCategories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+	parents := make(map[int64]*int64, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+
+	net := order.SubtotalAmount - order.DiscountAmount
+	for i := range order.Items {
+		item := &order.Items[i]
+
+		// Категория товара и все её родители, начиная с ближайшей
+		var lineage []int64
+		id := products[item.ProductID].CategoryID
+		for depth := 0; id != nil && depth <= len(parents); id, depth = parents[*id], depth+1 {
+			lineage = append(lineage, *id)
+		}
+		rate := domain.FindTaxRate(rates, lineage, country, region)
+		if rate == nil {
+			continue
+		}
+
+		base, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return fmt.Errorf("failed to calculate tax: %w", err)
+		}
+		if order.DiscountAmount != 0 {
+			if base, err = net.Prorate(base, order.SubtotalAmount); err != nil {
+				return fmt.Errorf("failed to calculate tax: %w", err)
+			}
+		}
+		tax, err := base.Tax(rate.Rate, order.TaxInclusive)
+		if err != nil {
+			return fmt.Errorf("failed to calculate tax: %w", err)
+		}
+		item.TaxRate = rate.Rate
+		item.TaxAmount = tax
+		order.TaxAmount += tax
+	}
+	return nil
+}
+
+// orderTotal is what the customer is charged for the order.
+func orderTotal(order *domain.Order) money.Amount {
+	total := order.SubtotalAmount - order.DiscountAmount
+	if !order.TaxInclusive {
+		total += order.TaxAmount
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/order-service/internal/webhook"
+)
+
+const (
+	// webhookDeliveriesLimit is how many recent deliveries are listed per subscription.
+	webhookDeliveriesLimit = 50
+	webhookSecretPrefix    = "whsec_"
+)
+
+// CreateWebhookSubscription registers an endpoint for the given event types.
+// The signing secret is generated here and returned only in this response.
+func (s *Service) CreateWebhookSubscription(ctx context.Context, req *WebhookSubscriptionRequest) (*domain.WebhookSubscription, error) {
+	verr := &domain.ValidationError{}
+	endpoint, err := url.Parse(strings.TrimSpace(req.URL))
+	switch {
+	case err != nil || endpoint.Host == "":
+		verr.Add("url", "must be an absolute https URL")
+	case webhook.ValidateURL(endpoint, s.cfg.AllowInsecureWebhooks) != nil:
+		// Адреса внутренней сети недоступны партнёрам и открывают SSRF
+		verr.Add("url", "must be an https URL of a public host")
+	}
+
+	if len(req.EventTypes) == 0 {
+		verr.Add("event_types", "at least one event type is required")
+	}
+	seen := make(map[domain.WebhookEventType]bool, len(req.EventTypes))
+	eventTypes := make([]domain.WebhookEventType, 0, len(req.EventTypes))
+	for _, eventType := range req.EventTypes {
+		if !isWebhookEventType(eventType) {
+			verr.Add("event_types", fmt.Sprintf("unknown event type %q", eventType))
+			continue
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if err := verr.OrNil(); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	sub := &domain.WebhookSubscription{
+		ID:          uuid.New(),
+		URL:         endpoint.String(),
+		EventTypes:  eventTypes,
+		Description: req.Description,
+		Secret:      webhookSecretPrefix + hex.EncodeToString(secret),
+		Active:      true,
+	}
+	if err := s.webhookRepo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) ListWebhookSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return s.webhookRepo.ListSubscriptions(ctx)
+}
+
+func (s *Service) GetWebhookSubscription(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	return s.webhookRepo.GetSubscription(ctx, id)
+}
+
+func (s *Service) DeleteWebhookSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.webhookRepo.DeleteSubscription(ctx, id)
+}
+
+// EnableWebhookSubscription re-activates a subscription, e.g. one disabled
+// after repeated delivery failures.
+func (s *Service) EnableWebhookSubscription(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	if err := s.webhookRepo.EnableSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.GetSubscription(ctx, id)
+}
+
+// ListWebhookDeliveries returns the recent deliveries of a subscription with their attempts.
+func (s *Service) ListWebhookDeliveries(ctx context.Context, id uuid.UUID) ([]*domain.WebhookDelivery, error) {
+	if _, err := s.webhookRepo.GetSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.GetDeliveries(ctx, id, webhookDeliveriesLimit)
+}
+
+func isWebhookEventType(eventType domain.WebhookEventType) bool {
+	for _, known := range domain.WebhookEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
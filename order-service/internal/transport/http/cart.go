@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) GetCart(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.service.GetCart(r.Context(), mux.Vars(r)["user_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeCart(w, cart)
+}
+
+func (h *Handler) SetCartItem(w http.ResponseWriter, r *http.Request) {
+	var req service.CartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	cart, err := h.service.SetCartItem(r.Context(), mux.Vars(r)["user_id"], &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeCart(w, cart)
+}
+
+func (h *Handler) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	productID, ok := productIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	cart, err := h.service.RemoveCartItem(r.Context(), mux.Vars(r)["user_id"], productID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeCart(w, cart)
+}
+
+func (h *Handler) ClearCart(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.ClearCart(r.Context(), mux.Vars(r)["user_id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) CheckoutCart(w http.ResponseWriter, r *http.Request) {
+	// Тело запроса необязательно
+	var req service.CheckoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	order, err := h.service.CheckoutCart(r.Context(), mux.Vars(r)["user_id"], &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func writeCart(w http.ResponseWriter, cart *domain.Cart) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const correlationIDHeader = "X-Correlation-ID"
+
+// withCorrelationID puts the request's correlation ID into its context, so
+// that the outbox messages it causes carry it to Kafka. Requests without one
+// get a new ID; either way it is echoed back in the response.
+func withCorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(correlationIDHeader)
+		if id == "" || len(id) > 255 {
+			id = uuid.NewString()
+		}
+		w.Header().Set(correlationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(domain.WithCorrelationID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+const (
+	// sseHeartbeatInterval is how often an idle stream sends a comment to keep
+	// proxies from closing the connection. Each heartbeat also re-reads the
+	// status history, which picks up changes made by other instances.
+	sseHeartbeatInterval = 15 * time.Second
+	// sseRetry is the reconnection delay suggested to the client, in milliseconds.
+	sseRetry = 3000
+)
+
+// StreamUserOrderEvents streams the status changes of the user's orders as
+// Server-Sent Events. The event ID is the ID of the status history entry, so
+// a client reconnecting with Last-Event-ID receives every change it missed.
+// Without it the stream starts from the current state.
+func (h *Handler) StreamUserOrderEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, &Problem{Status: http.StatusInternalServerError, Code: codeInternalError, Detail: "streaming is not supported"})
+		return
+	}
+	ctx := r.Context()
+	userID := mux.Vars(r)["user_id"]
+
+	// EventSource не позволяет задать заголовок при первом подключении,
+	// поэтому позиция принимается и из параметра запроса
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	// Подписываемся до чтения истории, чтобы не пропустить изменение между ними
+	wake, unsubscribe := h.service.SubscribeStatusChanges()
+	defer unsubscribe()
+
+	var (
+		cursor int64
+		err    error
+	)
+	if lastID != "" {
+		cursor, err = strconv.ParseInt(lastID, 10, 64)
+		if err != nil || cursor < 0 {
+			writeBadRequest(w, "invalid Last-Event-ID")
+			return
+		}
+	} else if cursor, err = h.service.GetLastUserStatusChangeID(ctx, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		// Отправляем все изменения после курсора; пачка может быть неполной
+		// только на последней итерации
+		for {
+			changes, err := h.service.GetUserStatusChanges(ctx, userID, cursor)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to read status changes of user %s: %v", userID, err)
+				}
+				return
+			}
+			for _, change := range changes {
+				data, err := json.Marshal(change)
+				if err != nil {
+					log.Printf("Failed to marshal status change %d: %v", change.ID, err)
+					return
+				}
+				fmt.Fprintf(w, "id: %d\nevent: order_status\ndata: %s\n\n", change.ID, data)
+				cursor = change.ID
+			}
+			if len(changes) > 0 {
+				flusher.Flush()
+			}
+			if len(changes) < service.StatusChangeBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -2,11 +2,18 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
 	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type Handler struct {
@@ -20,35 +27,210 @@ func NewHandler(s *service.Service) *Handler {
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
+	r.Use(withCorrelationID)
 	r.HandleFunc("/products", h.ListProducts).Methods(http.MethodGet)
-	r.HandleFunc("/orders", h.CreateOrder).Methods(http.MethodPost)
+	r.HandleFunc("/products", h.CreateProduct).Methods(http.MethodPost)
+	r.HandleFunc("/products/{product_id}", h.GetProduct).Methods(http.MethodGet)
+	r.HandleFunc("/products/{product_id}", h.ReplaceProduct).Methods(http.MethodPut)
+	r.HandleFunc("/products/{product_id}", h.PatchProduct).Methods(http.MethodPatch)
+	r.HandleFunc("/products/{product_id}", h.DeleteProduct).Methods(http.MethodDelete)
+	r.HandleFunc("/categories", h.ListCategories).Methods(http.MethodGet)
+	r.HandleFunc("/categories", h.CreateCategory).Methods(http.MethodPost)
+	r.HandleFunc("/promo-codes", h.ListPromoCodes).Methods(http.MethodGet)
+	r.HandleFunc("/promo-codes", h.CreatePromoCode).Methods(http.MethodPost)
+	r.HandleFunc("/tax-rates", h.ListTaxRates).Methods(http.MethodGet)
+	r.HandleFunc("/tax-rates", h.CreateTaxRate).Methods(http.MethodPost)
+	r.HandleFunc("/tax-rates/{tax_rate_id}", h.DeleteTaxRate).Methods(http.MethodDelete)
+	r.HandleFunc("/webhooks", h.ListWebhookSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks", h.CreateWebhookSubscription).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks/{webhook_id}", h.GetWebhookSubscription).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/{webhook_id}", h.DeleteWebhookSubscription).Methods(http.MethodDelete)
+	r.HandleFunc("/webhooks/{webhook_id}/enable", h.EnableWebhookSubscription).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks/{webhook_id}/deliveries", h.ListWebhookDeliveries).Methods(http.MethodGet)
+	r.HandleFunc("/carts/{user_id}/items", h.GetCart).Methods(http.MethodGet)
+	r.HandleFunc("/carts/{user_id}/items", h.SetCartItem).Methods(http.MethodPut)
+	r.HandleFunc("/carts/{user_id}/items", h.ClearCart).Methods(http.MethodDelete)
+	r.HandleFunc("/carts/{user_id}/items/{product_id}", h.RemoveCartItem).Methods(http.MethodDelete)
+	r.HandleFunc("/carts/{user_id}/checkout", h.idempotent(h.CheckoutCart)).Methods(http.MethodPost)
+	r.HandleFunc("/orders", h.idempotent(h.CreateOrder)).Methods(http.MethodPost)
 	r.HandleFunc("/orders", h.GetAllOrders).Methods(http.MethodGet)
 	r.HandleFunc("/orders/{order_id}", h.GetOrderByID).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/cancel", h.CancelOrder).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/reorder", h.idempotent(h.Reorder)).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/history", h.GetOrderHistory).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/returns", h.CreateReturn).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/returns", h.ListReturns).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/returns/{return_id}", h.GetReturn).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/returns/{return_id}/approve", h.ApproveReturn).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/shipments", h.CreateShipment).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/shipments", h.ListShipments).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/shipments/{shipment_id}", h.GetShipment).Methods(http.MethodGet)
+	r.HandleFunc("/orders/{order_id}/shipments/{shipment_id}/ship", h.ShipShipment).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/shipments/{shipment_id}/deliver", h.DeliverShipment).Methods(http.MethodPost)
+	r.HandleFunc("/orders/{order_id}/shipments/{shipment_id}/return", h.ReturnShipment).Methods(http.MethodPost)
 	r.HandleFunc("/orders/user/{user_id}", h.GetUserOrders).Methods(http.MethodGet)
+	r.HandleFunc("/orders/user/{user_id}/events", h.StreamUserOrderEvents).Methods(http.MethodGet)
 }
 
 func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := h.service.ListProducts(r.Context())
+	q := r.URL.Query()
+	filter, err := parseProductFilter(q)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	result, err := h.service.SearchProducts(r.Context(), *filter, q.Get("category"))
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.ListCategories(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req service.CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	category, err := h.service.CreateCategory(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.service.GetProduct(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	var req service.ProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	product, err := h.service.CreateProduct(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *Handler) ReplaceProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.ProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	product, err := h.service.ReplaceProduct(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *Handler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var patch service.ProductPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	product, err := h.service.PatchProduct(r.Context(), id, &patch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := productIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteProduct(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func productIDFromRequest(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["product_id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, "invalid product ID")
+		return 0, false
+	}
+	return id, true
 }
 
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err.Error())
 		return
 	}
 
 	order, err := h.service.CreateOrder(r.Context(), &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -58,14 +240,20 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
-	orders, err := h.service.GetAllOrders(r.Context())
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	page, err := h.service.ListOrders(r.Context(), *filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(page)
 }
 
 func (h *Handler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
@@ -74,18 +262,18 @@ func (h *Handler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 
 	id, err := uuid.Parse(orderID)
 	if err != nil {
-		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		writeBadRequest(w, "invalid order ID")
 		return
 	}
 
 	order, err := h.service.GetOrderByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
 	if order == nil {
-		http.Error(w, "order not found", http.StatusNotFound)
+		writeError(w, domain.ErrOrderNotFound)
 		return
 	}
 
@@ -93,16 +281,244 @@ func (h *Handler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(order)
 }
 
+type cancelOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	// Тело запроса необязательно
+	var req cancelOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	order, err := h.service.CancelOrder(r.Context(), id, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *Handler) Reorder(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	// Тело запроса необязательно
+	var req service.ReorderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	reorder, err := h.service.Reorder(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reorder)
+}
+
+func (h *Handler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	history, err := h.service.GetOrderHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
 func (h *Handler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 
-	orders, err := h.service.GetUserOrders(r.Context(), userID)
+	filter, err := parseOrderFilter(r.URL.Query())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeBadRequest(w, err.Error())
+		return
+	}
+	filter.UserID = userID
+
+	page, err := h.service.ListOrders(r.Context(), *filter)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseOrderFilter builds an order listing filter from query parameters:
+// status (comma-separated), created_from, created_to, currency, min_amount,
+// max_amount, sort, cursor and limit.
+func parseOrderFilter(q url.Values) (*domain.OrderFilter, error) {
+	filter := &domain.OrderFilter{}
+
+	if v := q.Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			switch s := domain.OrderStatus(strings.ToUpper(strings.TrimSpace(status))); s {
+			case domain.StatusNew, domain.StatusFinished, domain.StatusCancelled:
+				filter.Statuses = append(filter.Statuses, s)
+			default:
+				return nil, fmt.Errorf("invalid status: %s", status)
+			}
+		}
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseTimeParam(q, "created_from", false); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = parseTimeParam(q, "created_to", true); err != nil {
+		return nil, err
+	}
+	if v := q.Get("currency"); v != "" {
+		if filter.Currency, err = money.ParseCurrency(v); err != nil {
+			return nil, err
+		}
+	}
+	if filter.MinAmount, err = parseAmountParam(q, "min_amount"); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = parseAmountParam(q, "max_amount"); err != nil {
+		return nil, err
+	}
+	if (filter.MinAmount != nil || filter.MaxAmount != nil) && filter.Currency == "" {
+		return nil, fmt.Errorf("currency is required with min_amount and max_amount")
+	}
+
+	switch sort := domain.OrderSort(q.Get("sort")); sort {
+	case "", domain.SortCreatedAtDesc, domain.SortCreatedAtAsc, domain.SortTotalAmountDesc, domain.SortTotalAmountAsc:
+		filter.Sort = sort
+	default:
+		return nil, fmt.Errorf("invalid sort: %s", sort)
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := domain.DecodeOrderCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		// Курсор одной сортировки бессмыслен для другой
+		if (cursor.TotalAmount != nil) != filter.Sort.ByAmount() {
+			return nil, domain.ErrInvalidCursor
+		}
+		filter.After = cursor
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// parseProductFilter builds a product search filter from query parameters:
+// q, currency, min_price, max_price, sort, limit and offset. The category parameter
+// is resolved by the service.
+func parseProductFilter(q url.Values) (*domain.ProductFilter, error) {
+	filter := &domain.ProductFilter{Query: strings.TrimSpace(q.Get("q"))}
+
+	var err error
+	if v := q.Get("currency"); v != "" {
+		if filter.Currency, err = money.ParseCurrency(v); err != nil {
+			return nil, err
+		}
+	}
+	if filter.MinPrice, err = parseAmountParam(q, "min_price"); err != nil {
+		return nil, err
+	}
+	if filter.MaxPrice, err = parseAmountParam(q, "max_price"); err != nil {
+		return nil, err
+	}
+
+	switch sort := domain.ProductSort(q.Get("sort")); sort {
+	case "", domain.SortRelevance, domain.SortPriceAsc, domain.SortPriceDesc, domain.SortNewest:
+		filter.Sort = sort
+	default:
+		return nil, fmt.Errorf("invalid sort: %s", sort)
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid offset: %s", v)
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a YYYY-MM-DD date.
+// A date means its start, or with endOfDay the start of the next day, so that
+// an exclusive upper bound such as created_to still includes the named day.
+func parseTimeParam(q url.Values, name string, endOfDay bool) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, v)
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	return &t, nil
+}
+
+func parseAmountParam(q url.Values, name string) (*money.Amount, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	amount, err := money.Parse(v)
+	if err != nil || amount < 0 {
+		return nil, fmt.Errorf("invalid %s: %s", name, v)
+	}
+	return &amount, nil
 }
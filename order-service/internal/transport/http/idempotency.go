@@ -0,0 +1,102 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 1 << 20
+)
+
+// responseRecorder captures the status and body written by a handler
+// while passing them through to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// idempotent makes a handler honor the Idempotency-Key header. The first
+// request with a key is processed and its successful response stored; a
+// retry with the same key and body gets that response back, and a retry
+// with a different body is rejected with 422. Failed requests release the
+// key so that they can be retried. Requests without the header are passed
+// through unchanged.
+func (h *Handler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > domain.MaxIdempotencyKeyLength {
+			writeBadRequest(w, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBytes))
+		if err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := h.service.BeginIdempotentRequest(r.Context(), key, requestFingerprint(r, body))
+		switch {
+		case err != nil:
+			writeError(w, err)
+			return
+		case record != nil:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(*record.StatusCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// Ответ уже отправлен клиенту, поэтому ключ сохраняем и при отмене запроса
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= 200 && rec.status < 300 {
+			err = h.service.CompleteIdempotentRequest(ctx, key, rec.status, rec.body.Bytes())
+		} else {
+			err = h.service.ReleaseIdempotentRequest(ctx, key)
+		}
+		if err != nil {
+			log.Printf("Failed to finalize idempotency key %s: %v", key, err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
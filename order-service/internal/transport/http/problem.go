@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response. Code is a stable machine-readable
+// identifier of the error; clients should branch on it rather than on Detail,
+// which is meant for humans and may change.
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Code      string                 `json:"code"`
+	Errors    []domain.FieldError    `json:"errors,omitempty"`    // Invalid fields of a validation_failed problem
+	Shortages []domain.StockShortage `json:"shortages,omitempty"` // Missing stock of an insufficient_stock problem
+}
+
+// Stable error codes that are not tied to a single domain error.
+const (
+	codeInvalidRequest   = "invalid_request"
+	codeValidationFailed = "validation_failed"
+	codeInternalError    = "internal_error"
+)
+
+// errorProblems maps domain errors to the status and code of their problem.
+// Matching uses errors.Is, so wrapped errors keep their code.
+var errorProblems = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrProductNotFound, http.StatusNotFound, "product_not_found"},
+	{domain.ErrCategoryNotFound, http.StatusNotFound, "category_not_found"},
+	{domain.ErrOrderNotFound, http.StatusNotFound, "order_not_found"},
+	{domain.ErrReturnNotFound, http.StatusNotFound, "return_not_found"},
+	{domain.ErrShipmentNotFound, http.StatusNotFound, "shipment_not_found"},
+	{domain.ErrPromoCodeNotFound, http.StatusNotFound, "promo_code_not_found"},
+	{domain.ErrTaxRateNotFound, http.StatusNotFound, "tax_rate_not_found"},
+	{domain.ErrWebhookSubscriptionNotFound, http.StatusNotFound, "webhook_not_found"},
+	{domain.ErrStockBelowReserved, http.StatusConflict, "stock_below_reserved"},
+	{domain.ErrCartEmpty, http.StatusConflict, "cart_empty"},
+	{domain.ErrCartChanged, http.StatusConflict, "cart_changed"},
+	{domain.ErrNothingToReorder, http.StatusConflict, "nothing_to_reorder"},
+	{domain.ErrPromoCodeExhausted, http.StatusConflict, "promo_code_exhausted"},
+	{domain.ErrOrderNotCancellable, http.StatusConflict, "order_not_cancellable"},
+	{domain.ErrOrderNotReturnable, http.StatusConflict, "order_not_returnable"},
+	{domain.ErrOrderNotShippable, http.StatusConflict, "order_not_shippable"},
+	{domain.ErrInvalidStatusTransition, http.StatusConflict, "invalid_status_transition"},
+	{domain.ErrStatusWindowExpired, http.StatusConflict, "status_window_expired"},
+	{domain.ErrInvalidReturnTransition, http.StatusConflict, "invalid_return_transition"},
+	{domain.ErrInvalidShipmentTransition, http.StatusConflict, "invalid_shipment_transition"},
+	{domain.ErrIdempotencyKeyInProgress, http.StatusConflict, "idempotency_key_in_progress"},
+	{domain.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+	{money.ErrOverflow, http.StatusUnprocessableEntity, "amount_out_of_range"},
+	{money.ErrPrecision, http.StatusUnprocessableEntity, "amount_precision"},
+}
+
+// writeError writes the problem of err. Validation errors list the invalid
+// fields; errors without a mapping are logged and reported as internal
+// errors without their details.
+func writeError(w http.ResponseWriter, err error) {
+	var (
+		verr     *domain.ValidationError
+		stockErr *domain.InsufficientStockError
+	)
+	switch {
+	case errors.As(err, &verr):
+		writeProblem(w, &Problem{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeValidationFailed,
+			Detail: "the request has invalid fields",
+			Errors: verr.Fields,
+		})
+		return
+	case errors.As(err, &stockErr):
+		writeProblem(w, &Problem{
+			Status:    http.StatusConflict,
+			Code:      "insufficient_stock",
+			Detail:    stockErr.Error(),
+			Shortages: stockErr.Shortages,
+		})
+		return
+	}
+
+	for _, p := range errorProblems {
+		if errors.Is(err, p.err) {
+			writeProblem(w, &Problem{Status: p.status, Code: p.code, Detail: err.Error()})
+			return
+		}
+	}
+
+	log.Printf("Internal error: %v", err)
+	writeProblem(w, &Problem{Status: http.StatusInternalServerError, Code: codeInternalError})
+}
+
+// writeBadRequest reports a request that could not be parsed, such as a
+// malformed body, path parameter or query string.
+func writeBadRequest(w http.ResponseWriter, detail string) {
+	writeProblem(w, &Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: detail})
+}
+
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
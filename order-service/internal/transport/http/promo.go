@@ -0,0 +1,37 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) ListPromoCodes(w http.ResponseWriter, r *http.Request) {
+	promos, err := h.service.ListPromoCodes(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promos)
+}
+
+func (h *Handler) CreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	var req service.PromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	promo, err := h.service.CreatePromoCode(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promo)
+}
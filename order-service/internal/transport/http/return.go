@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	var req service.ReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	ret, err := h.service.CreateReturn(r.Context(), orderID, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (h *Handler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	returns, err := h.service.ListReturns(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(returns)
+}
+
+func (h *Handler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, returnID, ok := returnIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.GetReturn(r.Context(), orderID, returnID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (h *Handler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, returnID, ok := returnIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.ApproveReturn(r.Context(), orderID, returnID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+func returnIDsFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	vars := mux.Vars(r)
+	orderID, err := uuid.Parse(vars["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	returnID, err := uuid.Parse(vars["return_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid return ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return orderID, returnID, true
+}
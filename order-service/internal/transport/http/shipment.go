@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	// Тело запроса необязательно: без позиций упаковывается всё неотправленное
+	var req service.ShipmentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBadRequest(w, err.Error())
+			return
+		}
+	}
+
+	shipment, err := h.service.CreateShipment(r.Context(), orderID, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *Handler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return
+	}
+
+	shipments, err := h.service.ListShipments(r.Context(), orderID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipments)
+}
+
+func (h *Handler) GetShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, shipmentID, ok := shipmentIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	shipment, err := h.service.GetShipment(r.Context(), orderID, shipmentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *Handler) ShipShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, shipmentID, ok := shipmentIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.ShipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	shipment, err := h.service.ShipShipment(r.Context(), orderID, shipmentID, &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *Handler) DeliverShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, shipmentID, ok := shipmentIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	shipment, err := h.service.DeliverShipment(r.Context(), orderID, shipmentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *Handler) ReturnShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, shipmentID, ok := shipmentIDsFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	shipment, err := h.service.ReturnShipment(r.Context(), orderID, shipmentID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func shipmentIDsFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	vars := mux.Vars(r)
+	orderID, err := uuid.Parse(vars["order_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid order ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	shipmentID, err := uuid.Parse(vars["shipment_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid shipment ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return orderID, shipmentID, true
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.service.ListTaxRates(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+func (h *Handler) CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var req service.TaxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	rate, err := h.service.CreateTaxRate(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rate)
+}
+
+func (h *Handler) DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["tax_rate_id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeBadRequest(w, "invalid tax rate ID")
+		return
+	}
+
+	if err := h.service.DeleteTaxRate(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/order-service/internal/service"
+)
+
+func (h *Handler) CreateWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	var req service.WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	sub, err := h.service.CreateWebhookSubscription(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *Handler) ListWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListWebhookSubscriptions(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+func (h *Handler) GetWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetWebhookSubscription(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *Handler) DeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhookSubscription(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) EnableWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.EnableWebhookSubscription(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveries(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+func webhookIDFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["webhook_id"])
+	if err != nil {
+		writeBadRequest(w, "invalid webhook ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
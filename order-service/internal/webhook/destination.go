@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination is returned for webhook URLs that point into the
+// service's own network: loopback, private, link-local (including cloud
+// metadata endpoints) and unspecified addresses.
+var ErrForbiddenDestination = errors.New("webhook destination is not allowed")
+
+// ValidateURL checks a subscriber URL before it is stored. Outside
+// development only https is accepted, and hosts that are internal IP
+// literals or localhost are rejected. Host names are checked again after
+// DNS resolution when a delivery connects, see NewDispatcher.
+func ValidateURL(endpoint *url.URL, allowInsecure bool) error {
+	switch endpoint.Scheme {
+	case "https":
+	case "http":
+		if !allowInsecure {
+			return fmt.Errorf("%w: https is required", ErrForbiddenDestination)
+		}
+	default:
+		return fmt.Errorf("%w: scheme %q", ErrForbiddenDestination, endpoint.Scheme)
+	}
+	host := strings.ToLower(strings.TrimSuffix(endpoint.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: host is required", ErrForbiddenDestination)
+	}
+	if allowInsecure {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+	}
+	return nil
+}
+
+// isPublic reports whether addr is routable outside the service's network.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// newTransport returns a transport that connects only to public addresses.
+// The check runs on the resolved address of every connection, so DNS names
+// pointing inside the network and redirects to internal hosts are refused too.
+// Proxies from the environment are ignored: they would be dialled instead of
+// the subscriber and hide its address from the check.
+func newTransport(timeout time.Duration, allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+			}
+			if !isPublic(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenDestination, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,213 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/order-service/internal/domain"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-ID"
+)
+
+// Repository is the storage of queued deliveries.
+type Repository interface {
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, attempt *domain.WebhookAttempt, retryAt *time.Time, maxFailures int) error
+}
+
+// Config holds the delivery policy of the dispatcher.
+type Config struct {
+	// MaxAttempts is how many times a delivery is tried before it is marked failed.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles with every
+	// further attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// DisableAfter is how many failed attempts in a row disable a subscription.
+	DisableAfter int
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+	// AllowPrivateNetworks lets deliveries reach loopback and private
+	// addresses. It is meant for local development only.
+	AllowPrivateNetworks bool
+}
+
+// DefaultConfig retries for about a day before giving up on a delivery.
+var DefaultConfig = Config{
+	MaxAttempts:  10,
+	BaseBackoff:  30 * time.Second,
+	MaxBackoff:   6 * time.Hour,
+	DisableAfter: 20,
+	Timeout:      10 * time.Second,
+	PollInterval: 2 * time.Second,
+	BatchSize:    20,
+}
+
+// Envelope is the body POSTed to the subscriber. ID is the same for every
+// attempt of a delivery, so receivers can drop duplicates.
+type Envelope struct {
+	ID        uuid.UUID               `json:"id"`
+	Type      domain.WebhookEventType `json:"type"`
+	CreatedAt time.Time               `json:"created_at"`
+	Data      json.RawMessage         `json:"data"`
+}
+
+// Dispatcher delivers queued webhook events to subscribers.
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+	cfg    Config
+}
+
+func NewDispatcher(repo Repository, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: newTransport(cfg.Timeout, cfg.AllowPrivateNetworks),
+		},
+		cfg: cfg,
+	}
+}
+
+// Start polls for due deliveries until ctx is cancelled.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			if err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error dispatching webhooks: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DispatchDue sends one batch of due deliveries concurrently.
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	// Аренда с запасом перекрывает таймаут запроса, чтобы доставку
+	// не забрал другой экземпляр, пока она ещё выполняется
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, d.cfg.BatchSize, 2*d.cfg.Timeout+time.Minute)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *domain.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *domain.WebhookDelivery) {
+	attempt := &domain.WebhookAttempt{DeliveryID: delivery.ID}
+	started := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	attempt.DurationMS = int(time.Since(started).Milliseconds())
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else if !attempt.Succeeded() {
+		attempt.Error = fmt.Sprintf("unexpected status %d", statusCode)
+	}
+
+	var retryAt *time.Time
+	if !attempt.Succeeded() && delivery.Attempts+1 < d.cfg.MaxAttempts {
+		next := time.Now().Add(d.backoff(delivery.Attempts + 1))
+		retryAt = &next
+	}
+
+	// Результат сохраняется и при остановке сервиса, иначе попытка потеряется
+	if err := d.repo.RecordAttempt(context.WithoutCancel(ctx), attempt, retryAt, d.cfg.DisableAfter); err != nil {
+		log.Printf("Failed to record attempt of webhook delivery %s: %v", delivery.ID, err)
+		return
+	}
+	if !attempt.Succeeded() {
+		log.Printf("Webhook delivery %s to %s failed (attempt %d): %s", delivery.ID, delivery.URL, attempt.Attempt, attempt.Error)
+	}
+}
+
+// send POSTs the signed envelope and returns the response status, or 0 if
+// there was no response.
+func (d *Dispatcher) send(ctx context.Context, delivery *domain.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderID, delivery.EventID.String())
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the given failed attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// Sign returns the X-Webhook-Signature value for body: the timestamp and the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+// Receivers should recompute it and reject stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,14 @@
+-- +migrate Up
+ALTER TABLE order_items
+ADD COLUMN product_name VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE order_items oi
+SET product_name = p.name
+FROM products p
+WHERE p.id = oi.product_id;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_order_items_order_id;
+ALTER TABLE order_items DROP COLUMN product_name;
@@ -0,0 +1,6 @@
+-- +migrate Up
+ALTER TABLE orders
+ADD COLUMN cancel_reason TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN cancel_reason;
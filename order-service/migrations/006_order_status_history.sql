@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    source VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id, id);
+
+INSERT INTO order_status_history (order_id, from_status, to_status, reason, source, created_at)
+SELECT id, NULL, 'NEW', 'Order created', 'customer', created_at FROM orders;
+
+INSERT INTO order_status_history (order_id, from_status, to_status, reason, source, created_at)
+SELECT id, 'NEW', status, cancel_reason, 'system', updated_at FROM orders WHERE status <> 'NEW';
+
+-- +migrate Down
+DROP TABLE order_status_history;
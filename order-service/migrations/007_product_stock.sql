@@ -0,0 +1,15 @@
+-- +migrate Up
+ALTER TABLE products
+ADD COLUMN stock_quantity INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN reserved_quantity INTEGER NOT NULL DEFAULT 0,
+ADD CONSTRAINT products_stock_non_negative CHECK (stock_quantity >= 0),
+ADD CONSTRAINT products_reserved_within_stock CHECK (reserved_quantity >= 0 AND reserved_quantity <= stock_quantity);
+
+UPDATE products SET stock_quantity = 100;
+
+-- +migrate Down
+ALTER TABLE products
+DROP CONSTRAINT products_reserved_within_stock,
+DROP CONSTRAINT products_stock_non_negative,
+DROP COLUMN reserved_quantity,
+DROP COLUMN stock_quantity;
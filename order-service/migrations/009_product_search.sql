@@ -0,0 +1,44 @@
+-- +migrate Up
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+ALTER TABLE products
+ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_products_search_vector ON products USING GIN(search_vector);
+CREATE INDEX idx_products_category_id ON products(category_id);
+CREATE INDEX idx_products_price ON products(price);
+
+INSERT INTO categories (name, slug, parent_id) VALUES
+('Товары для дома', 'home', NULL),
+('Мебель', 'furniture', NULL),
+('Услуги', 'services', NULL);
+
+INSERT INTO categories (name, slug, parent_id) VALUES
+('Гигиена', 'hygiene', (SELECT id FROM categories WHERE slug = 'home')),
+('Хозяйственные товары', 'household', (SELECT id FROM categories WHERE slug = 'home'));
+
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'hygiene') WHERE name = 'Мыло';
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'household') WHERE name = 'Верёвка';
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'furniture') WHERE name = 'Стул';
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'services') WHERE name = 'VIP-статус';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_products_price;
+DROP INDEX IF EXISTS idx_products_category_id;
+DROP INDEX IF EXISTS idx_products_search_vector;
+ALTER TABLE products DROP COLUMN search_vector, DROP COLUMN category_id;
+DROP TABLE categories;
@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE products
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE orders
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE INDEX IF NOT EXISTS idx_products_currency ON products(currency);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_products_currency;
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE products DROP COLUMN currency;
@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS cart_items (
+    user_id VARCHAR(255) NOT NULL,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, product_id)
+);
+
+CREATE TRIGGER cart_items_set_updated_at
+BEFORE UPDATE ON cart_items
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS cart_items_set_updated_at ON cart_items;
+DROP TABLE IF EXISTS cart_items;
@@ -0,0 +1,79 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    discount_type VARCHAR(16) NOT NULL,
+    percent_off INT,
+    amount_off DECIMAL(10, 2),
+    currency CHAR(3),
+    min_order_amount DECIMAL(10, 2),
+    max_redemptions INT,
+    max_redemptions_per_user INT,
+    redemptions_count INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT promo_codes_discount_check CHECK (
+        (discount_type = 'percentage' AND percent_off BETWEEN 1 AND 100 AND amount_off IS NULL)
+        OR (discount_type = 'fixed' AND amount_off > 0 AND percent_off IS NULL AND currency IS NOT NULL)
+    ),
+    CONSTRAINT promo_codes_redemptions_check CHECK (max_redemptions IS NULL OR redemptions_count <= max_redemptions)
+);
+
+CREATE TRIGGER promo_codes_set_updated_at
+BEFORE UPDATE ON promo_codes
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- Ограничения по товарам и категориям; без строк промокод действует на весь заказ
+CREATE TABLE IF NOT EXISTS promo_code_products (
+    promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    product_id BIGINT NOT NULL REFERENCES products(id),
+    PRIMARY KEY (promo_code_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS promo_code_categories (
+    promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories(id),
+    PRIMARY KEY (promo_code_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_code_id BIGINT NOT NULL REFERENCES promo_codes(id),
+    order_id VARCHAR(255) NOT NULL UNIQUE REFERENCES orders(id),
+    user_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code_user ON promo_redemptions(promo_code_id, user_id);
+
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id BIGSERIAL PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(id),
+    promo_code_id BIGINT REFERENCES promo_codes(id),
+    code VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts(order_id);
+
+ALTER TABLE orders
+ADD COLUMN subtotal_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+ADD COLUMN discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+UPDATE orders SET subtotal_amount = total_amount;
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN discount_amount, DROP COLUMN subtotal_amount;
+DROP INDEX IF EXISTS idx_order_discounts_order_id;
+DROP TABLE IF EXISTS order_discounts;
+DROP INDEX IF EXISTS idx_promo_redemptions_code_user;
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_code_categories;
+DROP TABLE IF EXISTS promo_code_products;
+DROP TRIGGER IF EXISTS promo_codes_set_updated_at ON promo_codes;
+DROP TABLE IF EXISTS promo_codes;
@@ -0,0 +1,37 @@
+-- +migrate Up
+-- refund_amount — сумма к возврату в валюте заказа с учетом скидок заказа
+CREATE TABLE IF NOT EXISTS order_returns (
+    id UUID PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(id),
+    user_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'REQUESTED',
+    reason TEXT NOT NULL DEFAULT '',
+    refund_amount DECIMAL(10, 2) NOT NULL CHECK (refund_amount >= 0),
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_returns_order_id ON order_returns(order_id);
+
+CREATE TRIGGER order_returns_set_updated_at
+BEFORE UPDATE ON order_returns
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS order_return_items (
+    return_id UUID NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id),
+    product_id BIGINT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    refund_amount DECIMAL(10, 2) NOT NULL CHECK (refund_amount >= 0),
+    PRIMARY KEY (return_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_return_items_order_item_id ON order_return_items(order_item_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_order_return_items_order_item_id;
+DROP TABLE IF EXISTS order_return_items;
+DROP TRIGGER IF EXISTS order_returns_set_updated_at ON order_returns;
+DROP INDEX IF EXISTS idx_order_returns_order_id;
+DROP TABLE IF EXISTS order_returns;
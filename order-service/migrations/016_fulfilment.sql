@@ -0,0 +1,49 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS order_shipping_addresses (
+    order_id VARCHAR(255) PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    recipient VARCHAR(255) NOT NULL,
+    phone VARCHAR(32) NOT NULL DEFAULT '',
+    line1 VARCHAR(255) NOT NULL,
+    line2 VARCHAR(255) NOT NULL DEFAULT '',
+    city VARCHAR(128) NOT NULL,
+    region VARCHAR(128) NOT NULL DEFAULT '',
+    postal_code VARCHAR(32) NOT NULL,
+    country CHAR(2) NOT NULL
+);
+
+-- Отправление: часть позиций оплаченного заказа, упакованная на складе
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL REFERENCES orders(id),
+    status VARCHAR(16) NOT NULL DEFAULT 'PACKED',
+    carrier VARCHAR(64) NOT NULL DEFAULT '',
+    tracking_number VARCHAR(128) NOT NULL DEFAULT '',
+    shipped_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    returned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments(order_id);
+
+CREATE TRIGGER shipments_set_updated_at
+BEFORE UPDATE ON shipments
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    order_item_id BIGINT NOT NULL REFERENCES order_items(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (shipment_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipment_items_order_item_id ON shipment_items(order_item_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_shipment_items_order_item_id;
+DROP TABLE IF EXISTS shipment_items;
+DROP TRIGGER IF EXISTS shipments_set_updated_at ON shipments;
+DROP INDEX IF EXISTS idx_shipments_order_id;
+DROP TABLE IF EXISTS shipments;
+DROP TABLE IF EXISTS order_shipping_addresses;
@@ -0,0 +1,30 @@
+-- +migrate Up
+-- Ставка налога для страны или региона назначения; category_id NULL — для всех категорий.
+-- rate — десятичная доля, например 0.2 для 20%
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id SERIAL PRIMARY KEY,
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    country CHAR(2) NOT NULL,
+    region VARCHAR(128) NOT NULL DEFAULT '',
+    rate NUMERIC(6, 5) NOT NULL CHECK (rate >= 0 AND rate < 1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_rates_scope ON tax_rates(COALESCE(category_id, 0), country, region);
+
+ALTER TABLE order_items
+ADD COLUMN tax_rate NUMERIC(6, 5) NOT NULL DEFAULT 0,
+ADD COLUMN tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+-- tax_inclusive — цены позиций уже включают налог, и total_amount его не добавляет
+ALTER TABLE orders
+ADD COLUMN tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN tax_inclusive;
+ALTER TABLE orders DROP COLUMN tax_amount;
+ALTER TABLE order_items DROP COLUMN tax_amount;
+ALTER TABLE order_items DROP COLUMN tax_rate;
+DROP INDEX IF EXISTS idx_tax_rates_scope;
+DROP TABLE IF EXISTS tax_rates;
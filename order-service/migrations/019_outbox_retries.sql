@@ -0,0 +1,16 @@
+-- +migrate Up
+-- Неудачные публикации повторяются с нарастающей задержкой; после исчерпания попыток статус становится failed
+ALTER TABLE outbox_messages
+ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+ADD COLUMN last_error TEXT NOT NULL DEFAULT '',
+ADD COLUMN next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+DROP INDEX IF EXISTS idx_outbox_messages_pending;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages(next_attempt_at) WHERE status = 'pending';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages(created_at) WHERE status = 'pending';
+ALTER TABLE outbox_messages DROP COLUMN next_attempt_at;
+ALTER TABLE outbox_messages DROP COLUMN last_error;
+ALTER TABLE outbox_messages DROP COLUMN attempts;
@@ -0,0 +1,7 @@
+-- +migrate Up
+-- ID запроса, вызвавшего событие; публикуется в заголовке correlation_id записи Kafka
+ALTER TABLE outbox_messages
+ADD COLUMN correlation_id VARCHAR(255) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE outbox_messages DROP COLUMN correlation_id;
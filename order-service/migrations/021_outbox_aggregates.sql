@@ -0,0 +1,32 @@
+-- +migrate Up
+-- Сообщения одного агрегата публикуются по порядку seq и с ключом aggregate_id
+ALTER TABLE outbox_messages
+ADD COLUMN aggregate_type VARCHAR(64) NOT NULL DEFAULT '',
+ADD COLUMN aggregate_id VARCHAR(255) NOT NULL DEFAULT '',
+ADD COLUMN seq BIGSERIAL;
+
+-- BIGSERIAL нумерует существующие строки в порядке их хранения;
+-- перенумеровываем их в порядке создания
+UPDATE outbox_messages m
+SET seq = o.rn
+FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS rn FROM outbox_messages) o
+WHERE m.id = o.id;
+
+SELECT setval(pg_get_serial_sequence('outbox_messages', 'seq'), (SELECT COALESCE(MAX(seq), 0) + 1 FROM outbox_messages), false);
+
+-- Все события сервиса относятся к заказу; неопубликованные получают его агрегат
+UPDATE outbox_messages
+SET aggregate_type = 'order', aggregate_id = payload->>'order_id'
+WHERE status = 'pending' AND payload ? 'order_id';
+
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages(seq) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_aggregate ON outbox_messages(aggregate_type, aggregate_id, seq) WHERE status = 'pending';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_outbox_messages_aggregate;
+DROP INDEX IF EXISTS idx_outbox_messages_due;
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages(next_attempt_at) WHERE status = 'pending';
+ALTER TABLE outbox_messages DROP COLUMN seq;
+ALTER TABLE outbox_messages DROP COLUMN aggregate_id;
+ALTER TABLE outbox_messages DROP COLUMN aggregate_type;
@@ -7,14 +7,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/kafka"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/migration"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/repository/postgres"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/service"
 	phttp "github.com/mnntn/ecommerce-project/payment-service/internal/transport/http"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
 func main() {
@@ -45,21 +49,40 @@ func main() {
 	accountRepo := postgres.NewAccountRepository(db)
 	inboxRepo := postgres.NewInboxRepository(db)
 	outboxRepo := postgres.NewOutboxRepository(db)
+	rateRepo := postgres.NewExchangeRateRepository(db)
 
 	// Сервис аккаунтов
 	accountService := service.NewAccountService(accountRepo, userRepo)
+	rateService := service.NewExchangeRateService(rateRepo)
 
 	// Обработчик заказов с transactional inbox/outbox
 	orderProcessor := service.NewOrderProcessor(accountRepo, producer, inboxRepo, outboxRepo, db)
 
-	// OutboxProcessor для transactional outbox
-	outboxProcessor := postgres.NewOutboxProcessor(outboxRepo, producer)
-	outboxProcessor.Start()
-
 	// Start message processing
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Уведомления о новых сообщениях outbox; без них процессор только опрашивает таблицу
+	var outboxWakeups outbox.Wakeups
+	outboxListener, err := outbox.NewListener(os.Getenv("DB_CONNECTION_STRING"))
+	if err != nil {
+		log.Printf("Outbox listener unavailable, falling back to polling: %v", err)
+	} else {
+		defer outboxListener.Close()
+		outboxWakeups = outboxListener
+	}
+
+	// Процессор transactional outbox; реплики делят очередь через аренду сообщений
+	outbox.NewProcessor(outboxRepo, producer, outbox.Config{
+		BatchSize:    intFromEnv("OUTBOX_BATCH_SIZE", outbox.DefaultConfig.BatchSize),
+		Workers:      intFromEnv("OUTBOX_WORKERS", outbox.DefaultConfig.Workers),
+		Lease:        durationFromEnv("OUTBOX_LEASE", outbox.DefaultConfig.Lease),
+		PollInterval: durationFromEnv("OUTBOX_POLL_INTERVAL", outbox.DefaultConfig.PollInterval),
+		MaxAttempts:  intFromEnv("OUTBOX_MAX_ATTEMPTS", outbox.DefaultConfig.MaxAttempts),
+		BaseBackoff:  durationFromEnv("OUTBOX_BASE_BACKOFF", outbox.DefaultConfig.BaseBackoff),
+		MaxBackoff:   durationFromEnv("OUTBOX_MAX_BACKOFF", outbox.DefaultConfig.MaxBackoff),
+	}, outboxWakeups).Start(ctx)
+
 	go func() {
 		for {
 			select {
@@ -67,25 +90,26 @@ func main() {
 				log.Println("Kafka consumer stopped due to context cancellation")
 				return
 			default:
-				event, err := consumer.ReadOrderCreatedEvent(ctx)
+				event, err := consumer.ReadOrderEvent(ctx)
 				if err != nil {
 					if ctx.Err() != nil {
 						log.Println("Kafka consumer stopped due to context cancellation")
 						return
 					}
-					log.Printf("Error reading order created event: %v", err)
+					log.Printf("Error reading order event: %v", err)
 					continue
 				}
 
-				if err := orderProcessor.ProcessOrderCreated(ctx, event); err != nil {
-					log.Printf("Error processing order created: %v", err)
+				eventCtx := domain.WithCorrelationID(ctx, event.CorrelationID)
+				if err := orderProcessor.ProcessOrderEvent(eventCtx, event.Type, event.Payload); err != nil {
+					log.Printf("Error processing %s: %v", event.Type, err)
 				}
 			}
 		}
 	}()
 
 	// HTTP handler
-	handler := phttp.NewHandler(accountService)
+	handler := phttp.NewHandler(accountService, rateService)
 	r := mux.NewRouter()
 	handler.RegisterRoutes(r)
 
@@ -103,3 +127,29 @@ func main() {
 
 	log.Println("Shutting down...")
 }
+
+// durationFromEnv reads a time.Duration such as "30s" from the environment.
+func durationFromEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// intFromEnv reads a positive integer from the environment.
+func intFromEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid %s: must be a positive integer", key)
+	}
+	return n
+}
@@ -7,6 +7,7 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/lib/pq v1.10.9
+	github.com/mnntn/ecommerce-project/pkg v0.0.0-00010101000000-000000000000
 	github.com/segmentio/kafka-go v0.4.47
 )
 
@@ -16,3 +17,5 @@ require (
 	github.com/stretchr/testify v1.8.4 // indirect
 	golang.org/x/net v0.22.0 // indirect
 )
+
+replace github.com/mnntn/ecommerce-project/pkg => ../pkg
@@ -2,30 +2,40 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+var (
+	ErrAccountNotFound      = errors.New("account not found")
+	ErrAccountAlreadyExists = errors.New("account already exists")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrUserNotFound         = errors.New("user not found")
 )
 
 type Account struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    string    `json:"user_id"`
-	Balance   float64   `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID      `json:"id"`
+	UserID    string         `json:"user_id"`
+	Balance   money.Amount   `json:"balance"`
+	Currency  money.Currency `json:"currency"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
 }
 
 type AccountRepository interface {
 	Create(ctx context.Context, account *Account) error
 	GetByUserID(ctx context.Context, userID string) (*Account, error)
-	UpdateBalance(ctx context.Context, userID string, amount float64) error
+	UpdateBalance(ctx context.Context, userID string, amount money.Amount) error
 }
 
 type AccountService interface {
-	CreateAccount(userID string) (*Account, error)
+	CreateAccount(userID string, currency string) (*Account, error)
 	GetAccount(userID string) (*Account, error)
-	Deposit(userID string, amount float64) error
-	Withdraw(userID string, amount float64) error
+	Deposit(userID string, amount money.Amount) error
+	Withdraw(userID string, amount money.Amount) error
 }
 
 // User represents a user in the system
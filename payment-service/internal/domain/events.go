@@ -1,14 +1,43 @@
 package domain
 
 import (
+	"encoding/json"
+
 	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+// Типы событий топика orders (заголовок "type" сообщения Kafka)
+const (
+	EventOrderCreated         = "order_created"
+	EventOrderCancelRequested = "order_cancel_requested"
+	EventOrderReturnApproved  = "order_return_approved"
+)
+
+// Типы событий топика payments
+const (
+	EventOrderStatusUpdated  = "order_status_updated"
+	EventOrderReturnRefunded = "order_return_refunded"
 )
 
 // OrderCreatedEvent событие создания заказа
 type OrderCreatedEvent struct {
-	OrderID     uuid.UUID `json:"order_id"`
-	UserID      string    `json:"user_id"`
-	TotalAmount float64   `json:"total_amount"`
+	OrderID     uuid.UUID    `json:"order_id"`
+	UserID      string       `json:"user_id"`
+	TotalAmount money.Amount `json:"total_amount"`
+	// Currency пуст в событиях, опубликованных до появления валют
+	Currency money.Currency `json:"currency"`
+}
+
+// UnmarshalJSON округляет total_amount до копеек: события, опубликованные
+// до перехода на точные суммы, содержат float64 вида 199.98000000000002.
+func (e *OrderCreatedEvent) UnmarshalJSON(data []byte) error {
+	type event OrderCreatedEvent
+	wire := struct {
+		*event
+		TotalAmount *money.LegacyAmount `json:"total_amount"`
+	}{(*event)(e), (*money.LegacyAmount)(&e.TotalAmount)}
+	return json.Unmarshal(data, &wire)
 }
 
 // OrderStatusUpdatedEvent событие обновления статуса заказа
@@ -17,3 +46,58 @@ type OrderStatusUpdatedEvent struct {
 	Status  string `json:"status"`
 	Reason  string `json:"reason,omitempty"`
 }
+
+// OrderCancelRequestedEvent событие отмены заказа покупателем
+type OrderCancelRequestedEvent struct {
+	OrderID uuid.UUID `json:"order_id"`
+	UserID  string    `json:"user_id"`
+	Reason  string    `json:"reason"`
+}
+
+// OrderReturnApprovedEvent событие одобрения возврата части заказа.
+// Amount — сумма к возврату в валюте заказа
+type OrderReturnApprovedEvent struct {
+	ReturnID uuid.UUID         `json:"return_id"`
+	OrderID  uuid.UUID         `json:"order_id"`
+	UserID   string            `json:"user_id"`
+	Amount   money.Amount      `json:"amount"`
+	Currency money.Currency    `json:"currency"`
+	Items    []ReturnItemEvent `json:"items"`
+}
+
+// UnmarshalJSON округляет amount до копеек, как и OrderCreatedEvent
+func (e *OrderReturnApprovedEvent) UnmarshalJSON(data []byte) error {
+	type event OrderReturnApprovedEvent
+	wire := struct {
+		*event
+		Amount *money.LegacyAmount `json:"amount"`
+	}{(*event)(e), (*money.LegacyAmount)(&e.Amount)}
+	return json.Unmarshal(data, &wire)
+}
+
+// ReturnItemEvent возвращаемая позиция заказа
+type ReturnItemEvent struct {
+	OrderItemID int64        `json:"item_id"`
+	ProductID   int64        `json:"product_id"`
+	Quantity    int          `json:"quantity"`
+	Amount      money.Amount `json:"amount"`
+}
+
+// UnmarshalJSON округляет amount до копеек, как и OrderCreatedEvent
+func (e *ReturnItemEvent) UnmarshalJSON(data []byte) error {
+	type event ReturnItemEvent
+	wire := struct {
+		*event
+		Amount *money.LegacyAmount `json:"amount"`
+	}{(*event)(e), (*money.LegacyAmount)(&e.Amount)}
+	return json.Unmarshal(data, &wire)
+}
+
+// OrderReturnRefundedEvent событие зачисления возврата на счёт.
+// Amount и Currency — фактически зачисленная сумма в валюте счёта
+type OrderReturnRefundedEvent struct {
+	ReturnID uuid.UUID      `json:"return_id"`
+	OrderID  uuid.UUID      `json:"order_id"`
+	Amount   money.Amount   `json:"amount"`
+	Currency money.Currency `json:"currency"`
+}
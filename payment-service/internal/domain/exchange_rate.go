@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+var ErrExchangeRateNotFound = errors.New("exchange rate not found")
+
+// ExchangeRate курс пересчёта: 1 единица Base равна Rate единиц Quote.
+// Rate хранится десятичной строкой, чтобы не терять точность.
+type ExchangeRate struct {
+	Base      money.Currency `json:"base_currency"`
+	Quote     money.Currency `json:"quote_currency"`
+	Rate      string         `json:"rate"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
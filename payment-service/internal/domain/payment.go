@@ -0,0 +1,12 @@
+package domain
+
+// Статусы платежа по заказу. Платёж хранится в единственном экземпляре на заказ
+// и служит ключом идемпотентности для списания и возврата.
+const (
+	PaymentProcessing = "processing"
+	PaymentCompleted  = "completed"
+	PaymentFailed     = "failed"
+	PaymentRefunded   = "refunded"
+	// PaymentCancelled означает, что заказ отменён до списания средств.
+	PaymentCancelled = "cancelled"
+)
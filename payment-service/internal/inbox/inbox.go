@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type InboxMessage struct {
@@ -70,11 +71,11 @@ func (p *InboxProcessor) ProcessPendingMessages(ctx context.Context) error {
 	return nil
 }
 
-func CreatePaymentRequestMessage(orderID uuid.UUID, userID string, amount float64) (*InboxMessage, error) {
+func CreatePaymentRequestMessage(orderID uuid.UUID, userID string, amount money.Amount) (*InboxMessage, error) {
 	payload := struct {
-		OrderID uuid.UUID `json:"order_id"`
-		UserID  string    `json:"user_id"`
-		Amount  float64   `json:"amount"`
+		OrderID uuid.UUID    `json:"order_id"`
+		UserID  string       `json:"user_id"`
+		Amount  money.Amount `json:"amount"`
 	}{
 		OrderID: orderID,
 		UserID:  userID,
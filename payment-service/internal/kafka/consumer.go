@@ -49,12 +49,42 @@ func (c *Consumer) ReadOrderCreatedEvent(ctx context.Context) (*domain.OrderCrea
 		return nil, fmt.Errorf("failed to unmarshal OrderCreatedEvent: %w", err)
 	}
 
-	log.Printf("OrderCreatedEvent received: OrderID=%s, UserID=%d, Amount=%.2f",
+	log.Printf("OrderCreatedEvent received: OrderID=%s, UserID=%s, Amount=%s",
 		event.OrderID, event.UserID, event.TotalAmount)
 
 	return &event, nil
 }
 
+// OrderEvent сообщение топика orders вместе с его типом и correlation ID
+// запроса, вызвавшего событие
+type OrderEvent struct {
+	Type          string
+	CorrelationID string
+	Payload       []byte
+}
+
+// ReadOrderEvent читает очередное сообщение топика orders. Тип берётся из
+// заголовка "type"; сообщения без заголовка считаются событием создания заказа.
+func (c *Consumer) ReadOrderEvent(ctx context.Context) (*OrderEvent, error) {
+	msg, err := c.reader.ReadMessage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	event := &OrderEvent{Type: domain.EventOrderCreated, Payload: msg.Value}
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderType:
+			event.Type = string(h.Value)
+		case HeaderCorrelationID:
+			event.CorrelationID = string(h.Value)
+		}
+	}
+
+	log.Printf("Order event received: Type=%s, Payload=%s", event.Type, string(msg.Value))
+	return event, nil
+}
+
 func (c *Consumer) Close() error {
 	return c.reader.Close()
 }
@@ -5,26 +5,77 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"time"
 
 	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
-	"github.com/mnntn/ecommerce-project/payment-service/internal/repository/postgres"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 	"github.com/segmentio/kafka-go"
 )
 
+const (
+	PaymentsTopic = "payments"
+	// Source is the value of the source header of every record.
+	Source = "payment-service"
+)
+
+// Заголовки, которые получает каждая запись, опубликованная из outbox
+const (
+	HeaderMessageID     = "message_id"
+	HeaderType          = "type"
+	HeaderSchemaVersion = "schema_version"
+	HeaderSource        = "source"
+	HeaderTimestamp     = "timestamp"
+	HeaderCorrelationID = "correlation_id"
+	HeaderAggregateType = "aggregate_type"
+	HeaderAggregateID   = "aggregate_id"
+)
+
+// Route задаёт топик сообщений одного типа и версию схемы их payload
+type Route struct {
+	Topic         string
+	SchemaVersion int
+}
+
+// Routes сопоставляет типы сообщений outbox топикам. Для нового типа события
+// достаточно добавить запись сюда.
+var Routes = map[string]Route{
+	domain.EventOrderStatusUpdated:  {Topic: PaymentsTopic, SchemaVersion: 1},
+	domain.EventOrderReturnRefunded: {Topic: PaymentsTopic, SchemaVersion: 1},
+}
+
+// DefaultRoute используется для типов, которых нет в Routes
+var DefaultRoute = Route{Topic: PaymentsTopic, SchemaVersion: 1}
+
 type Producer struct {
 	writer *kafka.Writer
+	routes map[string]Route
 }
 
 func NewProducer(brokers []string) *Producer {
+	// Топик задаётся в каждой записи, поэтому один writer обслуживает все топики.
+	// Партиция выбирается по хешу ключа, чтобы события заказа шли по порядку
 	writer := &kafka.Writer{
-		Addr:     kafka.TCP(brokers[0]),
-		Topic:    "payments",
-		Balancer: &kafka.LeastBytes{},
+		Addr:                   kafka.TCP(brokers[0]),
+		Balancer:               &kafka.Hash{},
+		AllowAutoTopicCreation: true,
+		// Сообщения пишутся по одному и синхронно; по умолчанию writer ждёт
+		// заполнения пачки до секунды, что задерживало бы каждую публикацию
+		BatchTimeout: 5 * time.Millisecond,
 	}
 
 	return &Producer{
 		writer: writer,
+		routes: Routes,
+	}
+}
+
+// Route возвращает маршрут для типа сообщения
+func (p *Producer) Route(messageType string) Route {
+	if route, ok := p.routes[messageType]; ok {
+		return route
 	}
+	return DefaultRoute
 }
 
 func (p *Producer) SendMessage(ctx context.Context, key string, value interface{}) error {
@@ -34,6 +85,7 @@ func (p *Producer) SendMessage(ctx context.Context, key string, value interface{
 	}
 
 	msg := kafka.Message{
+		Topic: PaymentsTopic,
 		Key:   []byte(key),
 		Value: valueBytes,
 	}
@@ -54,15 +106,40 @@ func (p *Producer) Close() error {
 	return p.writer.Close()
 }
 
-// Publish реализует интерфейс OutboxPublisher
-func (p *Producer) Publish(ctx context.Context, message *postgres.OutboxMessage) error {
+// Publish реализует интерфейс outbox.Publisher. Топик выбирается по типу
+// сообщения, заголовки позволяют потребителям различать события, отбрасывать
+// дубликаты по message_id и связывать их с исходным запросом.
+func (p *Producer) Publish(ctx context.Context, message *outbox.Message) error {
+	route := p.Route(message.Type)
+	// Сообщение, не вызванное запросом, начинает собственную цепочку
+	correlationID := message.CorrelationID
+	if correlationID == "" {
+		correlationID = message.ID.String()
+	}
+	// События одного агрегата получают один ключ и попадают в одну партицию
+	key := message.AggregateID
+	if key == "" {
+		key = message.ID.String()
+	}
 	msg := kafka.Message{
-		Key:   message.ID[:],
+		Topic: route.Topic,
+		Key:   []byte(key),
 		Value: message.Payload,
+		Time:  message.CreatedAt,
+		Headers: []kafka.Header{
+			{Key: HeaderMessageID, Value: []byte(message.ID.String())},
+			{Key: HeaderType, Value: []byte(message.Type)},
+			{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(route.SchemaVersion))},
+			{Key: HeaderSource, Value: []byte(Source)},
+			{Key: HeaderTimestamp, Value: []byte(message.CreatedAt.UTC().Format(time.RFC3339Nano))},
+			{Key: HeaderCorrelationID, Value: []byte(correlationID)},
+			{Key: HeaderAggregateType, Value: []byte(message.AggregateType)},
+			{Key: HeaderAggregateID, Value: []byte(message.AggregateID)},
+		},
 	}
 	if err := p.writer.WriteMessages(ctx, msg); err != nil {
-		return fmt.Errorf("failed to write message to topic: %w", err)
+		return fmt.Errorf("failed to write message to topic %s: %w", route.Topic, err)
 	}
-	log.Printf("Outbox message sent to Kafka: %s", string(message.Payload))
+	log.Printf("Outbox message %s sent to Kafka topic %s: %s", message.Type, route.Topic, string(message.Payload))
 	return nil
 }
@@ -3,15 +3,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
-var ErrAccountAlreadyExists = errors.New("account already exists")
-
 type AccountRepository struct {
 	db *sql.DB
 }
@@ -22,8 +20,8 @@ func NewAccountRepository(db *sql.DB) *AccountRepository {
 
 func (r *AccountRepository) Create(ctx context.Context, account *domain.Account) error {
 	query := `
-		INSERT INTO accounts (id, user_id, balance, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO accounts (id, user_id, balance, currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
 	now := time.Now()
@@ -34,13 +32,14 @@ func (r *AccountRepository) Create(ctx context.Context, account *domain.Account)
 		account.ID,
 		account.UserID,
 		account.Balance,
+		account.Currency,
 		account.CreatedAt,
 		account.UpdatedAt,
 	)
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrAccountAlreadyExists
+			return domain.ErrAccountAlreadyExists
 		}
 		return err
 	}
@@ -50,7 +49,7 @@ func (r *AccountRepository) Create(ctx context.Context, account *domain.Account)
 
 func (r *AccountRepository) GetByUserID(ctx context.Context, userID string) (*domain.Account, error) {
 	query := `
-		SELECT id, user_id, balance, created_at, updated_at
+		SELECT id, user_id, balance, currency, created_at, updated_at
 		FROM accounts
 		WHERE user_id = $1
 	`
@@ -60,6 +59,7 @@ func (r *AccountRepository) GetByUserID(ctx context.Context, userID string) (*do
 		&account.ID,
 		&account.UserID,
 		&account.Balance,
+		&account.Currency,
 		&account.CreatedAt,
 		&account.UpdatedAt,
 	)
@@ -75,7 +75,7 @@ func (r *AccountRepository) GetByUserID(ctx context.Context, userID string) (*do
 	return account, nil
 }
 
-func (r *AccountRepository) UpdateBalance(ctx context.Context, userID string, amount float64) error {
+func (r *AccountRepository) UpdateBalance(ctx context.Context, userID string, amount money.Amount) error {
 	query := `
 		UPDATE accounts
 		SET balance = balance + $1, updated_at = $2
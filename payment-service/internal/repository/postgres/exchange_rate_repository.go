@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+type ExchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+func (r *ExchangeRateRepository) GetAll(ctx context.Context) ([]*domain.ExchangeRate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT base_currency, quote_currency, rate, updated_at
+		FROM exchange_rates
+		ORDER BY base_currency, quote_currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*domain.ExchangeRate{}
+	for rows.Next() {
+		rate := &domain.ExchangeRate{}
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// Upsert сохраняет курс, заменяя предыдущее значение для той же пары валют
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rate *domain.ExchangeRate) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO exchange_rates (base_currency, quote_currency, rate, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (base_currency, quote_currency)
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+		RETURNING rate, updated_at
+	`, rate.Base, rate.Quote, rate.Rate).Scan(&rate.Rate, &rate.UpdatedAt)
+}
+
+// GetRateTx возвращает курс пересчёта из base в quote внутри транзакции
+func GetRateTx(ctx context.Context, tx *sql.Tx, base, quote money.Currency) (string, error) {
+	var rate string
+	err := tx.QueryRowContext(ctx,
+		`SELECT rate FROM exchange_rates WHERE base_currency = $1 AND quote_currency = $2`,
+		base, quote,
+	).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return "", domain.ErrExchangeRateNotFound
+	}
+	return rate, err
+}
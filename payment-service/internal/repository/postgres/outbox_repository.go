@@ -3,83 +3,40 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"time"
 
-	"github.com/google/uuid"
+	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
-type OutboxMessage struct {
-	ID        uuid.UUID
-	Type      string
-	Payload   []byte
-	Status    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-}
-
+// OutboxRepository сохраняет сообщения outbox; захват и завершение их
+// процессором общие для сервисов и реализованы в outbox.PostgresRepository
 type OutboxRepository struct {
+	*outbox.PostgresRepository
 	db *sql.DB
 }
 
 func NewOutboxRepository(db *sql.DB) *OutboxRepository {
-	return &OutboxRepository{db: db}
+	return &OutboxRepository{PostgresRepository: outbox.NewPostgresRepository(db), db: db}
 }
 
-func (r *OutboxRepository) Save(ctx context.Context, message *OutboxMessage) error {
+func (r *OutboxRepository) Save(ctx context.Context, message *outbox.Message) error {
+	if message.CorrelationID == "" {
+		message.CorrelationID = domain.CorrelationID(ctx)
+	}
 	query := `
-		INSERT INTO outbox_messages (id, type, payload, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO outbox_messages (id, type, aggregate_type, aggregate_id, payload, status, correlation_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		message.ID,
 		message.Type,
+		message.AggregateType,
+		message.AggregateID,
 		message.Payload,
 		message.Status,
+		message.CorrelationID,
 		message.CreatedAt,
 		message.UpdatedAt,
 	)
 	return err
 }
-
-func (r *OutboxRepository) GetPending(ctx context.Context, limit int) ([]*OutboxMessage, error) {
-	query := `
-		SELECT id, type, payload, status, created_at, updated_at
-		FROM outbox_messages
-		WHERE status = 'pending'
-		ORDER BY created_at ASC
-		LIMIT $1
-	`
-	rows, err := r.db.QueryContext(ctx, query, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var messages []*OutboxMessage
-	for rows.Next() {
-		msg := &OutboxMessage{}
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Type,
-			&msg.Payload,
-			&msg.Status,
-			&msg.CreatedAt,
-			&msg.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		messages = append(messages, msg)
-	}
-	return messages, nil
-}
-
-func (r *OutboxRepository) MarkAsProcessed(ctx context.Context, id uuid.UUID) error {
-	query := `
-		UPDATE outbox_messages
-		SET status = 'processed', updated_at = $1
-		WHERE id = $2
-	`
-	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
-	return err
-}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type PaymentRepository interface {
@@ -17,12 +18,12 @@ type PaymentRepository interface {
 }
 
 type Payment struct {
-	ID        string    `db:"id"`
-	OrderID   string    `db:"order_id"`
-	Amount    float64   `db:"amount"`
-	Status    string    `db:"status"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID        string       `db:"id"`
+	OrderID   string       `db:"order_id"`
+	Amount    money.Amount `db:"amount"`
+	Status    string       `db:"status"`
+	CreatedAt time.Time    `db:"created_at"`
+	UpdatedAt time.Time    `db:"updated_at"`
 }
 
 type PostgresPaymentRepository struct {
@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/inbox"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type AccountRepository interface {
@@ -29,34 +31,46 @@ func NewAccountService(repo AccountRepository, userRepo domain.UserRepository) *
 	}
 }
 
-func (s *AccountService) GetBalance(ctx context.Context, userID string) (float64, error) {
-	account, err := s.repo.GetByUserID(ctx, userID)
+func (s *AccountService) GetBalance(ctx context.Context, userID string) (money.Amount, error) {
+	account, err := s.GetAccount(ctx, userID)
 	if err != nil {
 		return 0, err
 	}
 	return account.Balance, nil
 }
 
-func (s *AccountService) Deposit(ctx context.Context, userID string, amount float64) error {
-	account, err := s.repo.GetByUserID(ctx, userID)
+func (s *AccountService) Deposit(ctx context.Context, userID string, amount money.Amount) error {
+	if err := validateAmount(amount); err != nil {
+		return err
+	}
+	account, err := s.GetAccount(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	account.Balance += amount
+	balance, err := account.Balance.Add(amount)
+	if err != nil {
+		verr := &domain.ValidationError{}
+		verr.Add("amount", "balance would be out of range")
+		return verr
+	}
+	account.Balance = balance
 	account.UpdatedAt = time.Now()
 
 	return s.repo.Update(ctx, account)
 }
 
-func (s *AccountService) Withdraw(ctx context.Context, userID string, amount float64) error {
-	account, err := s.repo.GetByUserID(ctx, userID)
+func (s *AccountService) Withdraw(ctx context.Context, userID string, amount money.Amount) error {
+	if err := validateAmount(amount); err != nil {
+		return err
+	}
+	account, err := s.GetAccount(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	if account.Balance < amount {
-		return fmt.Errorf("insufficient funds")
+		return fmt.Errorf("%w: balance is %s", domain.ErrInsufficientFunds, account.Balance)
 	}
 
 	account.Balance -= amount
@@ -68,15 +82,16 @@ func (s *AccountService) Withdraw(ctx context.Context, userID string, amount flo
 func (s *AccountService) HandleMessage(ctx context.Context, message *inbox.InboxMessage) error {
 	switch message.Type {
 	case "payment_request":
+		// Запросы, сохранённые до перехода на точные суммы, содержат float64
 		var payload struct {
-			UserID string  `json:"user_id"`
-			Amount float64 `json:"amount"`
+			UserID string             `json:"user_id"`
+			Amount money.LegacyAmount `json:"amount"`
 		}
 		if err := json.Unmarshal([]byte(message.Payload), &payload); err != nil {
 			return fmt.Errorf("failed to unmarshal payment request: %w", err)
 		}
 
-		if err := s.Deposit(ctx, payload.UserID, payload.Amount); err != nil {
+		if err := s.Deposit(ctx, payload.UserID, money.Amount(payload.Amount)); err != nil {
 			return fmt.Errorf("failed to process payment: %w", err)
 		}
 
@@ -86,11 +101,23 @@ func (s *AccountService) HandleMessage(ctx context.Context, message *inbox.Inbox
 	}
 }
 
-func (s *AccountService) CreateAccount(ctx context.Context, userID string) (*domain.Account, error) {
+// CreateAccount открывает счёт в указанной валюте; по умолчанию — в money.DefaultCurrency
+func (s *AccountService) CreateAccount(ctx context.Context, userID string, currency string) (*domain.Account, error) {
+	accountCurrency := money.DefaultCurrency
+	if currency != "" {
+		var err error
+		if accountCurrency, err = money.ParseCurrency(currency); err != nil {
+			verr := &domain.ValidationError{}
+			verr.Add("currency", "must be a three-letter ISO 4217 code")
+			return nil, verr
+		}
+	}
+
 	account := &domain.Account{
 		ID:        uuid.New(),
 		UserID:    userID,
 		Balance:   0,
+		Currency:  accountCurrency,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -100,15 +127,40 @@ func (s *AccountService) CreateAccount(ctx context.Context, userID string) (*dom
 	return account, nil
 }
 
+// GetAccount returns the account of the user or domain.ErrAccountNotFound.
 func (s *AccountService) GetAccount(ctx context.Context, userID string) (*domain.Account, error) {
-	return s.repo.GetByUserID(ctx, userID)
+	account, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, domain.ErrAccountNotFound
+	}
+	return account, nil
 }
 
+// GetUser returns the user or domain.ErrUserNotFound, also for IDs that are not UUIDs.
 func (s *AccountService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
-	return s.userRepo.GetByID(uuid.MustParse(userID))
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
 }
 
 func (s *AccountService) CreateUser(ctx context.Context, name string) (*domain.User, error) {
+	if strings.TrimSpace(name) == "" {
+		verr := &domain.ValidationError{}
+		verr.Add("name", "is required")
+		return nil, verr
+	}
 	user := &domain.User{
 		ID:        uuid.New(),
 		Name:      name,
@@ -128,3 +180,12 @@ func (s *AccountService) GetAllUsers(ctx context.Context) ([]*domain.User, error
 	}
 	return nil, fmt.Errorf("GetAll not implemented in userRepo")
 }
+
+func validateAmount(amount money.Amount) error {
+	if !amount.IsPositive() {
+		verr := &domain.ValidationError{}
+		verr.Add("amount", "must be positive")
+		return verr
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+type ExchangeRateRepository interface {
+	GetAll(ctx context.Context) ([]*domain.ExchangeRate, error)
+	Upsert(ctx context.Context, rate *domain.ExchangeRate) error
+}
+
+type ExchangeRateService struct {
+	repo ExchangeRateRepository
+}
+
+func NewExchangeRateService(repo ExchangeRateRepository) *ExchangeRateService {
+	return &ExchangeRateService{repo: repo}
+}
+
+func (s *ExchangeRateService) ListRates(ctx context.Context) ([]*domain.ExchangeRate, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// SetRate записывает курс пересчёта из base в quote. Обратный курс
+// задаётся отдельно: он не обязан быть точной обратной величиной.
+func (s *ExchangeRateService) SetRate(ctx context.Context, base, quote, rate string) (*domain.ExchangeRate, error) {
+	baseCurrency, err := money.ParseCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	quoteCurrency, err := money.ParseCurrency(quote)
+	if err != nil {
+		return nil, err
+	}
+	if baseCurrency == quoteCurrency {
+		return nil, money.ErrInvalidRate
+	}
+	// big.Rat принимает и дроби вида "1/3", но в NUMERIC их не записать
+	if r, ok := new(big.Rat).SetString(rate); !ok || r.Sign() <= 0 || strings.Contains(rate, "/") {
+		return nil, money.ErrInvalidRate
+	}
+
+	exchangeRate := &domain.ExchangeRate{Base: baseCurrency, Quote: quoteCurrency, Rate: rate}
+	if err := s.repo.Upsert(ctx, exchangeRate); err != nil {
+		return nil, err
+	}
+	return exchangeRate, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/mnntn/ecommerce-project/payment-service/internal/inbox"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/kafka"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/repository/postgres"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+	"github.com/mnntn/ecommerce-project/pkg/outbox"
 )
 
 type OrderProcessor struct {
@@ -32,13 +36,40 @@ func NewOrderProcessor(accountRepo domain.AccountRepository, producer *kafka.Pro
 	}
 }
 
+// ProcessOrderEvent обрабатывает событие топика orders в зависимости от его типа
+func (p *OrderProcessor) ProcessOrderEvent(ctx context.Context, eventType string, payload []byte) error {
+	switch eventType {
+	case domain.EventOrderCreated:
+		var event domain.OrderCreatedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal OrderCreatedEvent: %w", err)
+		}
+		return p.ProcessOrderCreated(ctx, &event)
+	case domain.EventOrderCancelRequested:
+		var event domain.OrderCancelRequestedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal OrderCancelRequestedEvent: %w", err)
+		}
+		return p.ProcessOrderCancelRequested(ctx, &event)
+	case domain.EventOrderReturnApproved:
+		var event domain.OrderReturnApprovedEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal OrderReturnApprovedEvent: %w", err)
+		}
+		return p.ProcessOrderReturnApproved(ctx, &event)
+	default:
+		log.Printf("Skipping order event of unknown type: %s", eventType)
+		return nil
+	}
+}
+
 func (p *OrderProcessor) ProcessOrderCreated(ctx context.Context, event *domain.OrderCreatedEvent) error {
 	// Проверяем, не обработано ли уже событие (по id заказа)
 	inboxID := uuid.New() // Можно использовать event.OrderID, если он UUID
 	payload, _ := json.Marshal(event)
 	inboxMsg := &inbox.InboxMessage{
 		ID:        inboxID,
-		Type:      "order_created",
+		Type:      domain.EventOrderCreated,
 		Payload:   payload,
 		Status:    "pending",
 		CreatedAt: time.Now(),
@@ -68,51 +99,310 @@ func (p *OrderProcessor) ProcessOrderCreated(ctx context.Context, event *domain.
 		return err
 	}
 
+	// Создаём платёж по заказу. Если он уже есть, событие повторное
+	// либо заказ был отменён до оплаты — списание не выполняем.
+	orderID := event.OrderID.String()
+	orderCurrency := event.Currency.OrDefault()
+	claimed, err := p.insertPaymentTx(ctx, tx, orderID, event.UserID, event.TotalAmount, orderCurrency, domain.PaymentProcessing, "")
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		var paymentStatus string
+		err = tx.QueryRowContext(ctx, "SELECT status FROM payments WHERE order_id = $1", orderID).Scan(&paymentStatus)
+		if err != nil {
+			return err
+		}
+		if paymentStatus == domain.PaymentCancelled {
+			return p.saveOutboxAndCommitTx(ctx, tx, orderID, "CANCELLED", "Order cancelled before payment", inboxMsg.ID)
+		}
+		if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
 	// Получаем аккаунт пользователя
-	var balance float64
-	err = tx.QueryRowContext(ctx, "SELECT balance FROM accounts WHERE user_id = $1 FOR UPDATE", event.UserID).Scan(&balance)
+	var (
+		balance         money.Amount
+		accountCurrency money.Currency
+	)
+	err = tx.QueryRowContext(ctx, "SELECT balance, currency FROM accounts WHERE user_id = $1 FOR UPDATE", event.UserID).
+		Scan(&balance, &accountCurrency)
 	if err != nil {
-		p.markInboxProcessedTx(ctx, tx, inboxMsg.ID)
-		return p.saveOutboxAndCommitTx(ctx, tx, event.OrderID.String(), "CANCELLED", "Account not found", inboxMsg.ID)
+		return p.failPaymentAndCommitTx(ctx, tx, orderID, "Account not found", inboxMsg.ID)
 	}
 
-	if balance < event.TotalAmount {
-		p.markInboxProcessedTx(ctx, tx, inboxMsg.ID)
-		return p.saveOutboxAndCommitTx(ctx, tx, event.OrderID.String(), "CANCELLED", "Insufficient balance", inboxMsg.ID)
+	// Пересчитываем сумму заказа в валюту счёта
+	charge, rate := event.TotalAmount, "1"
+	if orderCurrency != accountCurrency {
+		rate, err = postgres.GetRateTx(ctx, tx, orderCurrency, accountCurrency)
+		if errors.Is(err, domain.ErrExchangeRateNotFound) {
+			reason := fmt.Sprintf("No exchange rate from %s to %s", orderCurrency, accountCurrency)
+			return p.failPaymentAndCommitTx(ctx, tx, orderID, reason, inboxMsg.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if charge, err = event.TotalAmount.Convert(rate); err != nil {
+			return err
+		}
+	}
+
+	if balance < charge {
+		return p.failPaymentAndCommitTx(ctx, tx, orderID, "Insufficient balance", inboxMsg.ID)
 	}
 
 	// Списываем средства
-	_, err = tx.ExecContext(ctx, "UPDATE accounts SET balance = balance - $1, updated_at = $2 WHERE user_id = $3", event.TotalAmount, time.Now(), event.UserID)
+	_, err = tx.ExecContext(ctx, "UPDATE accounts SET balance = balance - $1, updated_at = $2 WHERE user_id = $3", charge, time.Now(), event.UserID)
+	if err != nil {
+		return p.failPaymentAndCommitTx(ctx, tx, orderID, "Failed to withdraw funds", inboxMsg.ID)
+	}
+
+	// Сохраняем списанную сумму и курс для аудита
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET charged_amount = $1, charged_currency = $2, exchange_rate = $3 WHERE order_id = $4`,
+		charge, accountCurrency, rate, orderID)
 	if err != nil {
-		p.markInboxProcessedTx(ctx, tx, inboxMsg.ID)
-		return p.saveOutboxAndCommitTx(ctx, tx, event.OrderID.String(), "CANCELLED", "Failed to withdraw funds", inboxMsg.ID)
+		return err
+	}
+
+	if err := p.updatePaymentStatusTx(ctx, tx, orderID, domain.PaymentCompleted, "Payment successful"); err != nil {
+		return err
 	}
 
 	// Всё успешно — формируем событие FINISHED
-	return p.saveOutboxAndCommitTx(ctx, tx, event.OrderID.String(), "FINISHED", "Payment successful", inboxMsg.ID)
+	return p.saveOutboxAndCommitTx(ctx, tx, orderID, "FINISHED", "Payment successful", inboxMsg.ID)
 }
 
-func (p *OrderProcessor) saveOutboxAndCommitTx(ctx context.Context, tx *sql.Tx, orderID, status, reason string, inboxID uuid.UUID) error {
-	// Формируем событие
-	event := domain.OrderStatusUpdatedEvent{
-		OrderID: orderID,
-		Status:  status,
-		Reason:  reason,
+// ProcessOrderCancelRequested возвращает на счёт сумму, списанную за отменённый заказ.
+// Повторные события не приводят к повторному возврату: платёж переходит в статус refunded один раз.
+func (p *OrderProcessor) ProcessOrderCancelRequested(ctx context.Context, event *domain.OrderCancelRequestedEvent) error {
+	payload, _ := json.Marshal(event)
+	inboxMsg := &inbox.InboxMessage{
+		ID:        uuid.New(),
+		Type:      domain.EventOrderCancelRequested,
+		Payload:   payload,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO inbox_messages (id, type, payload, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		inboxMsg.ID, inboxMsg.Type, inboxMsg.Payload, inboxMsg.Status, inboxMsg.CreatedAt, inboxMsg.UpdatedAt)
+	if err != nil {
+		return err
 	}
+
+	// Заказ ещё не оплачен: помечаем платёж отменённым, чтобы событие
+	// создания заказа, пришедшее позже, не привело к списанию.
+	orderID := event.OrderID.String()
+	claimed, err := p.insertPaymentTx(ctx, tx, orderID, event.UserID, 0, money.DefaultCurrency, domain.PaymentCancelled, event.Reason)
+	if err != nil {
+		return err
+	}
+	if claimed {
+		if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+			return err
+		}
+		log.Printf("Order %s cancelled before payment", orderID)
+		return tx.Commit()
+	}
+
+	// Возвращаем списанную со счёта сумму в его валюте за вычетом уже
+	// возвращённого по частичным возвратам
+	var (
+		userID        string
+		amount        money.Amount
+		currency      money.Currency
+		paymentStatus string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, COALESCE(charged_amount, amount) - refunded_amount, COALESCE(charged_currency, currency), status
+		FROM payments WHERE order_id = $1 FOR UPDATE`, orderID).
+		Scan(&userID, &amount, &currency, &paymentStatus)
+	if err != nil {
+		return err
+	}
+
+	if paymentStatus == domain.PaymentCompleted {
+		_, err = tx.ExecContext(ctx, "UPDATE accounts SET balance = balance + $1, updated_at = $2 WHERE user_id = $3", amount, time.Now(), userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE payments SET refunded_amount = refunded_amount + $1 WHERE order_id = $2", amount, orderID)
+		if err != nil {
+			return err
+		}
+		if err := p.updatePaymentStatusTx(ctx, tx, orderID, domain.PaymentRefunded, event.Reason); err != nil {
+			return err
+		}
+		log.Printf("Refunded %s %s to user %s for cancelled order %s", amount, currency, userID, orderID)
+	}
+
+	if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ProcessOrderReturnApproved зачисляет на счёт сумму одобренного возврата части заказа.
+// Сумма пересчитывается по курсу, по которому был оплачен заказ, и не превышает
+// остатка списанной суммы. Возврат выполняется не более одного раза на return_id.
+func (p *OrderProcessor) ProcessOrderReturnApproved(ctx context.Context, event *domain.OrderReturnApprovedEvent) error {
 	payload, _ := json.Marshal(event)
-	outboxMsg := &postgres.OutboxMessage{
+	inboxMsg := &inbox.InboxMessage{
 		ID:        uuid.New(),
-		Type:      "order_status_updated",
+		Type:      domain.EventOrderReturnApproved,
 		Payload:   payload,
 		Status:    "pending",
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
-	_, err := tx.ExecContext(ctx, `INSERT INTO outbox_messages (id, type, payload, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
-		outboxMsg.ID, outboxMsg.Type, outboxMsg.Payload, outboxMsg.Status, outboxMsg.CreatedAt, outboxMsg.UpdatedAt)
+
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO inbox_messages (id, type, payload, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		inboxMsg.ID, inboxMsg.Type, inboxMsg.Payload, inboxMsg.Status, inboxMsg.CreatedAt, inboxMsg.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// Блокируем платёж, чтобы параллельные возвраты не превысили списанную сумму
+	orderID := event.OrderID.String()
+	var (
+		userID        string
+		remaining     money.Amount
+		currency      money.Currency
+		rate          string
+		paymentStatus string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, COALESCE(charged_amount, amount) - refunded_amount, COALESCE(charged_currency, currency),
+			COALESCE(exchange_rate, 1)::text, status
+		FROM payments WHERE order_id = $1 FOR UPDATE`, orderID).
+		Scan(&userID, &remaining, &currency, &rate, &paymentStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == sql.ErrNoRows || paymentStatus != domain.PaymentCompleted {
+		log.Printf("Skipping refund of return %s: order %s has no completed payment", event.ReturnID, orderID)
+		if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	amount, err := event.Amount.Convert(rate)
+	if err != nil {
+		return err
+	}
+	if amount > remaining {
+		amount = remaining
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO payment_refunds (return_id, order_id, user_id, amount, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (return_id) DO NOTHING`,
+		event.ReturnID, orderID, userID, amount, currency, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		// Возврат уже выполнен
+		if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE accounts SET balance = balance + $1, updated_at = $2 WHERE user_id = $3", amount, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	// Платёж считается возвращённым, когда возвращена вся списанная сумма
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payments
+		SET refunded_amount = refunded_amount + $1,
+			status = CASE WHEN refunded_amount + $1 >= COALESCE(charged_amount, amount) THEN $2 ELSE status END,
+			updated_at = $3
+		WHERE order_id = $4`,
+		amount, domain.PaymentRefunded, time.Now(), orderID)
+	if err != nil {
+		return err
+	}
+
+	err = p.insertOutboxTx(ctx, tx, domain.EventOrderReturnRefunded, orderID, domain.OrderReturnRefundedEvent{
+		ReturnID: event.ReturnID,
+		OrderID:  event.OrderID,
+		Amount:   amount,
+		Currency: currency,
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.markInboxProcessedTx(ctx, tx, inboxMsg.ID); err != nil {
+		return err
+	}
+	log.Printf("Refunded %s %s to user %s for return %s of order %s", amount, currency, userID, event.ReturnID, orderID)
+	return tx.Commit()
+}
+
+// insertPaymentTx создаёт платёж по заказу, если его ещё нет, и сообщает, был ли он создан
+func (p *OrderProcessor) insertPaymentTx(ctx context.Context, tx *sql.Tx, orderID, userID string, amount money.Amount, currency money.Currency, status, reason string) (bool, error) {
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO payments (id, order_id, user_id, amount, currency, status, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (order_id) DO NOTHING`,
+		uuid.New().String(), orderID, userID, amount, currency, status, reason, time.Now())
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (p *OrderProcessor) updatePaymentStatusTx(ctx context.Context, tx *sql.Tx, orderID, status, reason string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE payments SET status = $1, reason = $2, updated_at = $3 WHERE order_id = $4`,
+		status, reason, time.Now(), orderID)
+	return err
+}
+
+// failPaymentAndCommitTx помечает платёж неуспешным и отправляет событие отмены заказа
+func (p *OrderProcessor) failPaymentAndCommitTx(ctx context.Context, tx *sql.Tx, orderID, reason string, inboxID uuid.UUID) error {
+	if err := p.updatePaymentStatusTx(ctx, tx, orderID, domain.PaymentFailed, reason); err != nil {
+		return err
+	}
+	return p.saveOutboxAndCommitTx(ctx, tx, orderID, "CANCELLED", reason, inboxID)
+}
+
+func (p *OrderProcessor) saveOutboxAndCommitTx(ctx context.Context, tx *sql.Tx, orderID, status, reason string, inboxID uuid.UUID) error {
+	// Формируем событие
+	event := domain.OrderStatusUpdatedEvent{
+		OrderID: orderID,
+		Status:  status,
+		Reason:  reason,
+	}
+	if err := p.insertOutboxTx(ctx, tx, domain.EventOrderStatusUpdated, orderID, event); err != nil {
+		return err
+	}
 	// Помечаем inbox processed
 	if err := p.markInboxProcessedTx(ctx, tx, inboxID); err != nil {
 		return err
@@ -120,6 +410,29 @@ func (p *OrderProcessor) saveOutboxAndCommitTx(ctx context.Context, tx *sql.Tx,
 	return tx.Commit()
 }
 
+// insertOutboxTx сохраняет событие заказа orderID в outbox в рамках транзакции
+// вместе с correlation ID входящего события
+func (p *OrderProcessor) insertOutboxTx(ctx context.Context, tx *sql.Tx, eventType, orderID string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	outboxMsg := &outbox.Message{
+		ID:            uuid.New(),
+		Type:          eventType,
+		AggregateType: outbox.AggregateOrder,
+		AggregateID:   orderID,
+		Payload:       payload,
+		Status:        "pending",
+		CorrelationID: domain.CorrelationID(ctx),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox_messages (id, type, aggregate_type, aggregate_id, payload, status, correlation_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		outboxMsg.ID, outboxMsg.Type, outboxMsg.AggregateType, outboxMsg.AggregateID, outboxMsg.Payload, outboxMsg.Status, outboxMsg.CorrelationID, outboxMsg.CreatedAt, outboxMsg.UpdatedAt)
+	return err
+}
+
 func (p *OrderProcessor) markInboxProcessedTx(ctx context.Context, tx *sql.Tx, id uuid.UUID) error {
 	_, err := tx.ExecContext(ctx, `UPDATE inbox_messages SET status = 'processed', updated_at = $1 WHERE id = $2`, time.Now(), id)
 	return err
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mnntn/ecommerce-project/payment-service/internal/kafka"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/repository"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type PaymentService struct {
@@ -23,8 +24,19 @@ func NewPaymentService(repo repository.PaymentRepository, producer *kafka.Produc
 }
 
 type PaymentRequest struct {
-	OrderID string  `json:"order_id"`
-	Amount  float64 `json:"amount"`
+	OrderID string       `json:"order_id"`
+	Amount  money.Amount `json:"amount"`
+}
+
+// UnmarshalJSON округляет amount до копеек: запросы, сохранённые до перехода
+// на точные суммы, содержат float64
+func (r *PaymentRequest) UnmarshalJSON(data []byte) error {
+	type request PaymentRequest
+	wire := struct {
+		*request
+		Amount *money.LegacyAmount `json:"amount"`
+	}{(*request)(r), (*money.LegacyAmount)(&r.Amount)}
+	return json.Unmarshal(data, &wire)
 }
 
 func (s *PaymentService) ProcessPayment(ctx context.Context, msg []byte) error {
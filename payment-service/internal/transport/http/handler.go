@@ -2,21 +2,24 @@ package http
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
-	"github.com/mnntn/ecommerce-project/payment-service/internal/repository/postgres"
 	"github.com/mnntn/ecommerce-project/payment-service/internal/service"
+	"github.com/mnntn/ecommerce-project/pkg/money"
 )
 
 type Handler struct {
 	accountService *service.AccountService
+	rateService    *service.ExchangeRateService
 }
 
-func NewHandler(accountService *service.AccountService) *Handler {
+func NewHandler(accountService *service.AccountService, rateService *service.ExchangeRateService) *Handler {
 	return &Handler{
 		accountService: accountService,
+		rateService:    rateService,
 	}
 }
 
@@ -28,42 +31,49 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/users/{user_id}", h.GetUser).Methods(http.MethodGet)
 	r.HandleFunc("/users", h.CreateUser).Methods(http.MethodPost)
 	r.HandleFunc("/users", h.GetAllUsers).Methods(http.MethodGet)
+	r.HandleFunc("/exchange-rates", h.ListExchangeRates).Methods(http.MethodGet)
+	r.HandleFunc("/exchange-rates/{base}/{quote}", h.SetExchangeRate).Methods(http.MethodPut)
 }
 
 type createAccountRequest struct {
-	UserID string `json:"user_id"`
+	UserID   string `json:"user_id"`
+	Currency string `json:"currency"`
 }
 
 type accountResponse struct {
-	ID        string  `json:"id"`
-	UserID    string  `json:"user_id"`
-	Balance   float64 `json:"balance"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID        string       `json:"id"`
+	UserID    string       `json:"user_id"`
+	Balance   money.Amount `json:"balance"`
+	Currency  string       `json:"currency"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
 }
 
 type depositRequest struct {
-	Amount float64 `json:"amount"`
+	Amount money.Amount `json:"amount"`
 }
 
 type withdrawRequest struct {
-	Amount float64 `json:"amount"`
+	Amount money.Amount `json:"amount"`
 }
 
 func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	userID := r.Header.Get("X-User-ID")
 	if userID == "" {
-		http.Error(w, "user ID is required", http.StatusBadRequest)
+		writeBadRequest(w, "X-User-ID header is required")
 		return
 	}
 
-	account, err := h.accountService.CreateAccount(r.Context(), userID)
+	// Тело запроса необязательно: без него счёт открывается в валюте по умолчанию
+	var req createAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	account, err := h.accountService.CreateAccount(r.Context(), userID, req.Currency)
 	if err != nil {
-		if err == postgres.ErrAccountAlreadyExists {
-			http.Error(w, "account already exists", http.StatusConflict)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -78,12 +88,7 @@ func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
 
 	account, err := h.accountService.GetAccount(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if account == nil {
-		http.Error(w, "account not found", http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 
@@ -97,12 +102,12 @@ func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
 
 	var req depositRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err.Error())
 		return
 	}
 
 	if err := h.accountService.Deposit(r.Context(), userID, req.Amount); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -115,12 +120,12 @@ func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
 
 	var req withdrawRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err.Error())
 		return
 	}
 
 	if err := h.accountService.Withdraw(r.Context(), userID, req.Amount); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -133,11 +138,7 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.accountService.GetUser(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if user == nil {
-		http.Error(w, "user not found", http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -149,12 +150,12 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeBadRequest(w, err.Error())
 		return
 	}
 	user, err := h.accountService.CreateUser(r.Context(), req.Name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -165,18 +166,51 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.accountService.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
 
+type exchangeRateRequest struct {
+	Rate json.Number `json:"rate"`
+}
+
+func (h *Handler) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.rateService.ListRates(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+func (h *Handler) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, err.Error())
+		return
+	}
+
+	rate, err := h.rateService.SetRate(r.Context(), vars["base"], vars["quote"], req.Rate.String())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rate)
+}
+
 func mapAccountToResponse(account *domain.Account) *accountResponse {
 	return &accountResponse{
 		ID:        account.ID.String(),
 		UserID:    account.UserID,
 		Balance:   account.Balance,
+		Currency:  string(account.Currency),
 		CreatedAt: account.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt: account.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mnntn/ecommerce-project/payment-service/internal/domain"
+	"github.com/mnntn/ecommerce-project/pkg/money"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response. Code is a stable machine-readable
+// identifier of the error; clients should branch on it rather than on Detail,
+// which is meant for humans and may change.
+type Problem struct {
+	Type   string              `json:"type"`
+	Title  string              `json:"title"`
+	Status int                 `json:"status"`
+	Detail string              `json:"detail,omitempty"`
+	Code   string              `json:"code"`
+	Errors []domain.FieldError `json:"errors,omitempty"` // Invalid fields of a validation_failed problem
+}
+
+// Stable error codes that are not tied to a single domain error.
+const (
+	codeInvalidRequest   = "invalid_request"
+	codeValidationFailed = "validation_failed"
+	codeInternalError    = "internal_error"
+)
+
+// errorProblems maps domain errors to the status and code of their problem.
+// Matching uses errors.Is, so wrapped errors keep their code.
+var errorProblems = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrAccountNotFound, http.StatusNotFound, "account_not_found"},
+	{domain.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{domain.ErrExchangeRateNotFound, http.StatusNotFound, "exchange_rate_not_found"},
+	{domain.ErrAccountAlreadyExists, http.StatusConflict, "account_already_exists"},
+	{domain.ErrInsufficientFunds, http.StatusUnprocessableEntity, "insufficient_funds"},
+	{money.ErrInvalidCurrency, http.StatusUnprocessableEntity, "invalid_currency"},
+	{money.ErrInvalidRate, http.StatusUnprocessableEntity, "invalid_rate"},
+	{money.ErrOverflow, http.StatusUnprocessableEntity, "amount_out_of_range"},
+	{money.ErrPrecision, http.StatusUnprocessableEntity, "amount_precision"},
+}
+
+// writeError writes the problem of err. Validation errors list the invalid
+// fields; errors without a mapping are logged and reported as internal
+// errors without their details.
+func writeError(w http.ResponseWriter, err error) {
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) {
+		writeProblem(w, &Problem{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeValidationFailed,
+			Detail: "the request has invalid fields",
+			Errors: verr.Fields,
+		})
+		return
+	}
+
+	for _, p := range errorProblems {
+		if errors.Is(err, p.err) {
+			writeProblem(w, &Problem{Status: p.status, Code: p.code, Detail: err.Error()})
+			return
+		}
+	}
+
+	log.Printf("Internal error: %v", err)
+	writeProblem(w, &Problem{Status: http.StatusInternalServerError, Code: codeInternalError})
+}
+
+// writeBadRequest reports a request that could not be parsed, such as a
+// malformed body or a missing header.
+func writeBadRequest(w http.ResponseWriter, detail string) {
+	writeProblem(w, &Problem{Status: http.StatusBadRequest, Code: codeInvalidRequest, Detail: detail})
+}
+
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,10 @@
+-- +migrate Up
+ALTER TABLE payments
+ADD COLUMN user_id VARCHAR(255) NOT NULL DEFAULT '',
+ADD COLUMN reason TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_order_id_unique ON payments(order_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_payments_order_id_unique;
+ALTER TABLE payments DROP COLUMN reason, DROP COLUMN user_id;
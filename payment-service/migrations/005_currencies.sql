@@ -0,0 +1,33 @@
+-- +migrate Up
+ALTER TABLE accounts
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Курс: 1 единица base_currency = rate единиц quote_currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (base_currency, quote_currency)
+);
+
+-- amount и currency — сумма заказа; charged_* — фактически списанная со счёта
+-- сумма и курс, по которому она пересчитана
+ALTER TABLE payments
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD',
+ADD COLUMN charged_amount DECIMAL(18, 2),
+ADD COLUMN charged_currency CHAR(3),
+ADD COLUMN exchange_rate NUMERIC(20, 10);
+
+UPDATE payments
+SET charged_amount = amount, charged_currency = currency, exchange_rate = 1
+WHERE status IN ('completed', 'refunded');
+
+-- +migrate Down
+ALTER TABLE payments
+DROP COLUMN exchange_rate,
+DROP COLUMN charged_currency,
+DROP COLUMN charged_amount,
+DROP COLUMN currency;
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE accounts DROP COLUMN currency;
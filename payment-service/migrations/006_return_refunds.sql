@@ -0,0 +1,21 @@
+-- +migrate Up
+-- refunded_amount — сумма, уже возвращённая по частичным возвратам, в валюте списания
+ALTER TABLE payments
+ADD COLUMN refunded_amount DECIMAL(18, 2) NOT NULL DEFAULT 0;
+
+-- Один возврат на return_id: повторное событие не приводит к повторному зачислению
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    return_id UUID PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    amount DECIMAL(18, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_order_id ON payment_refunds(order_id);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_payment_refunds_order_id;
+DROP TABLE IF EXISTS payment_refunds;
+ALTER TABLE payments DROP COLUMN refunded_amount;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- Аренда сообщения экземпляром релея: пока locked_until в будущем, другие экземпляры его пропускают
+ALTER TABLE outbox_messages
+ADD COLUMN locked_by VARCHAR(255),
+ADD COLUMN locked_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages(created_at) WHERE status = 'pending';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_outbox_messages_pending;
+ALTER TABLE outbox_messages DROP COLUMN locked_until;
+ALTER TABLE outbox_messages DROP COLUMN locked_by;
@@ -0,0 +1,17 @@
+-- +migrate Up
+-- Уведомление будит процессоры outbox сразу после коммита, не дожидаясь опроса.
+-- Триггер уровня оператора: одно уведомление на вставку, одинаковые уведомления транзакции схлопываются
+CREATE OR REPLACE FUNCTION notify_outbox_messages() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('outbox_messages', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_messages_notify
+AFTER INSERT ON outbox_messages
+FOR EACH STATEMENT EXECUTE FUNCTION notify_outbox_messages();
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS outbox_messages_notify ON outbox_messages;
+DROP FUNCTION IF EXISTS notify_outbox_messages();
@@ -0,0 +1,8 @@
+module github.com/mnntn/ecommerce-project/pkg
+
+go 1.21
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+)
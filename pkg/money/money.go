@@ -0,0 +1,283 @@
+// Package money implements exact monetary amounts.
+//
+// Amounts are stored as an integer number of minor units (kopecks, cents),
+// so sums and comparisons never drift the way float64 arithmetic does.
+// In JSON and SQL an amount is a decimal with two fractional digits,
+// which keeps the wire format compatible with the DECIMAL columns and
+// with consumers that still decode amounts as floating point numbers.
+//
+// The package lives in the shared pkg module so that every service handles
+// money the same way.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of minor units in one major unit. Only currencies
+// with two fractional digits are supported.
+const Scale = 100
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+// DefaultCurrency is the currency of amounts recorded before currencies
+// were introduced.
+const DefaultCurrency Currency = "USD"
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrPrecision       = errors.New("amount has more than two fractional digits")
+	ErrOverflow        = errors.New("amount out of range")
+	ErrInvalidCurrency = errors.New("invalid currency")
+	ErrInvalidRate     = errors.New("invalid exchange rate")
+)
+
+// ParseCurrency validates a three-letter currency code, case-insensitively.
+func ParseCurrency(s string) (Currency, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, s)
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, s)
+		}
+	}
+	return Currency(s), nil
+}
+
+// OrDefault returns DefaultCurrency for an empty currency.
+func (c Currency) OrDefault() Currency {
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
+
+// Amount is a monetary amount in minor units.
+type Amount int64
+
+// FromMinor returns an amount of n minor units.
+func FromMinor(n int64) Amount {
+	return Amount(n)
+}
+
+// Parse parses a decimal string such as "199.99" exactly. Values with more
+// than two fractional digits are rejected rather than rounded.
+func Parse(s string) (Amount, error) {
+	r, err := parseRat(s)
+	if err != nil {
+		return 0, err
+	}
+	if !r.IsInt() {
+		return 0, fmt.Errorf("%w: %s", ErrPrecision, s)
+	}
+	return fromIntRat(r, s)
+}
+
+// ParseRounded parses a decimal string and rounds it half away from zero to
+// the nearest minor unit. It is meant for legacy payloads whose amounts
+// were produced by float64 arithmetic, e.g. "0.30000000000000004".
+func ParseRounded(s string) (Amount, error) {
+	r, err := parseRat(s)
+	if err != nil {
+		return 0, err
+	}
+	return fromIntRat(round(r), s)
+}
+
+// round rounds r half away from zero to an integer.
+func round(r *big.Rat) *big.Rat {
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	half := new(big.Int).Rsh(den, 1)
+	if num.Sign() < 0 {
+		num.Sub(num, half)
+	} else {
+		num.Add(num, half)
+	}
+	num.Quo(num, den)
+	return new(big.Rat).SetInt(num)
+}
+
+// parseRat returns s multiplied by Scale.
+func parseRat(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if s == "" || !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	return r.Mul(r, big.NewRat(Scale, 1)), nil
+}
+
+func fromIntRat(r *big.Rat, s string) (Amount, error) {
+	n := r.Num()
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, s)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// Minor returns the amount in minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Add returns the sum of two amounts.
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// Mul multiplies the amount by an integer quantity, which may be negative.
+func (a Amount) Mul(n int64) (Amount, error) {
+	r := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(n))
+	if !r.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return Amount(r.Int64()), nil
+}
+
+// Convert multiplies the amount by a decimal exchange rate such as
+// "0.0107" and rounds the result half away from zero to a minor unit.
+func (a Amount) Convert(rate string) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, rate)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return fromIntRat(round(r), rate)
+}
+
+// Percent returns pct percent of the amount, rounded half away from zero
+// to a minor unit.
+func (a Amount) Percent(pct int64) (Amount, error) {
+	r := new(big.Rat).SetFrac64(pct, 100)
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return fromIntRat(round(r), a.String())
+}
+
+// Prorate returns the share of the amount proportional to part/whole,
+// rounded half away from zero to a minor unit.
+func (a Amount) Prorate(part, whole Amount) (Amount, error) {
+	if whole == 0 {
+		return 0, fmt.Errorf("%w: prorate over zero", ErrInvalidAmount)
+	}
+	r := new(big.Rat).SetFrac(big.NewInt(int64(part)), big.NewInt(int64(whole)))
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return fromIntRat(round(r), a.String())
+}
+
+// Tax returns the tax on the amount at a decimal rate such as "0.2",
+// rounded half away from zero to a minor unit. If inclusive, the amount
+// already includes the tax and its tax portion is returned.
+func (a Amount) Tax(rate string, inclusive bool) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, rate)
+	}
+	if inclusive {
+		r.Quo(r, new(big.Rat).Add(r, big.NewRat(1, 1)))
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return fromIntRat(round(r), rate)
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// String formats the amount as a decimal with two fractional digits.
+func (a Amount) String() string {
+	n := int64(a)
+	sign := ""
+	var abs uint64
+	if n < 0 {
+		sign = "-"
+		abs = uint64(-(n + 1)) + 1
+	} else {
+		abs = uint64(n)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/Scale, abs%Scale)
+}
+
+// MarshalJSON encodes the amount as a JSON number, e.g. 199.99.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, a, Parse)
+}
+
+// LegacyAmount is an Amount that is decoded from JSON with ParseRounded.
+// Event decoders use it for amounts that may have been published before
+// amounts were exact, e.g. 199.98000000000002, by pointing it at the
+// Amount field being decoded:
+//
+//	Amount *money.LegacyAmount `json:"amount"`
+type LegacyAmount Amount
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal and
+// rounds it to the nearest minor unit.
+func (a *LegacyAmount) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, (*Amount)(a), ParseRounded)
+}
+
+func unmarshalJSON(data []byte, a *Amount, parse func(string) (Amount, error)) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns.
+func (a *Amount) Scan(src interface{}) error {
+	var (
+		v   Amount
+		err error
+	)
+	switch src := src.(type) {
+	case []byte:
+		v, err = Parse(string(src))
+	case string:
+		v, err = Parse(src)
+	case int64:
+		v, err = Amount(src).Mul(Scale)
+	case float64:
+		v, err = ParseRounded(strconv.FormatFloat(src, 'f', -1, 64))
+	case nil:
+		v = 0
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value implements driver.Valuer; the amount is sent as a decimal string.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
@@ -0,0 +1,339 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"199.99", 19999, nil},
+		{"0", 0, nil},
+		{" 5 ", 500, nil},
+		{"-0.01", -1, nil},
+		{"92233720368547758.07", math.MaxInt64, nil},
+		{"-92233720368547758.08", math.MinInt64, nil},
+		{"92233720368547758.08", 0, ErrOverflow},
+		{"1.005", 0, ErrPrecision},
+		{"0.30000000000000004", 0, ErrPrecision},
+		{"", 0, ErrInvalidAmount},
+		{"abc", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseRounded(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"0.30000000000000004", 30, nil},
+		{"1.004", 100, nil},
+		{"1.005", 101, nil},
+		{"-1.005", -101, nil},
+		{"2.675", 268, nil},
+		{"-0.004", 0, nil},
+		{"92233720368547758.075", 0, ErrOverflow},
+		{"x", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		got, err := ParseRounded(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ParseRounded(%q) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		a, b Amount
+		want Amount
+		err  error
+	}{
+		{1999, 1, 2000, nil},
+		{-250, 100, -150, nil},
+		{math.MaxInt64, 0, math.MaxInt64, nil},
+		{math.MaxInt64, 1, 0, ErrOverflow},
+		{math.MinInt64, -1, 0, ErrOverflow},
+		{math.MinInt64, math.MaxInt64, -1, nil},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Add(tt.b)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%d.Add(%d) = %d, %v; want %d, %v", tt.a, tt.b, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		n    int64
+		want Amount
+		err  error
+	}{
+		{1999, 3, 5997, nil},
+		{-250, 4, -1000, nil},
+		{100, -1, -100, nil},
+		{-100, -3, 300, nil},
+		{math.MaxInt64, 0, 0, nil},
+		{math.MaxInt64, 1, math.MaxInt64, nil},
+		{math.MaxInt64, 2, 0, ErrOverflow},
+		{math.MinInt64, -1, 0, ErrOverflow},
+		{math.MaxInt64/2 + 1, 2, 0, ErrOverflow},
+		{math.MinInt64 / 2, 2, math.MinInt64, nil},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Mul(tt.n)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%d.Mul(%d) = %d, %v; want %d, %v", tt.a, tt.n, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		rate string
+		want Amount
+		err  error
+	}{
+		{10000, "0.0107", 107, nil},
+		{12345, "1.5", 18518, nil},
+		{-12345, "1.5", -18518, nil},
+		{math.MaxInt64, "2", 0, ErrOverflow},
+		{100, "0", 0, ErrInvalidRate},
+		{100, "-1", 0, ErrInvalidRate},
+		{100, "abc", 0, ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Convert(tt.rate)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%d.Convert(%q) = %d, %v; want %d, %v", tt.a, tt.rate, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		pct  int64
+		want Amount
+	}{
+		{1000, 10, 100},
+		{1999, 15, 300},
+		{-1999, 15, -300},
+		{1999, 0, 0},
+		{1999, 100, 1999},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Percent(tt.pct)
+		if err != nil || got != tt.want {
+			t.Errorf("%d.Percent(%d) = %d, %v; want %d", tt.a, tt.pct, got, err, tt.want)
+		}
+	}
+}
+
+func TestProrate(t *testing.T) {
+	tests := []struct {
+		a           Amount
+		part, whole Amount
+		want        Amount
+		err         error
+	}{
+		{1000, 1, 3, 333, nil},
+		{1000, 2, 3, 667, nil},
+		{1000, 3, 3, 1000, nil},
+		{1000, 0, 3, 0, nil},
+		{-1000, 1, 3, -333, nil},
+		{-1000, 2, 3, -667, nil},
+		{1, 1, 2, 1, nil},
+		{-1, 1, 2, -1, nil},
+		{1000, 1, 0, 0, ErrInvalidAmount},
+		{math.MaxInt64, 3, 2, 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Prorate(tt.part, tt.whole)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%d.Prorate(%d, %d) = %d, %v; want %d, %v", tt.a, tt.part, tt.whole, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// Shares prorated one by one need not add up to the whole; callers give the
+// remainder to the last share.
+func TestProrateRemainder(t *testing.T) {
+	total := Amount(1000)
+	var sum Amount
+	for i := 0; i < 3; i++ {
+		share, err := total.Prorate(1, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += share
+	}
+	if sum != 999 {
+		t.Errorf("sum of three thirds of %d = %d; want 999", total, sum)
+	}
+}
+
+func TestTax(t *testing.T) {
+	tests := []struct {
+		a         Amount
+		rate      string
+		inclusive bool
+		want      Amount
+		err       error
+	}{
+		{1000, "0.2", false, 200, nil},
+		{1200, "0.2", true, 200, nil},
+		{999, "0.2", true, 167, nil},
+		{1999, "0.075", false, 150, nil},
+		{-1000, "0.2", false, -200, nil},
+		{-1200, "0.2", true, -200, nil},
+		{1000, "0", false, 0, nil},
+		{1000, "0", true, 0, nil},
+		{math.MaxInt64, "2", false, 0, ErrOverflow},
+		{1000, "-0.1", false, 0, ErrInvalidRate},
+		{1000, "x", false, 0, ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Tax(tt.rate, tt.inclusive)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%d.Tax(%q, %v) = %d, %v; want %d, %v", tt.a, tt.rate, tt.inclusive, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{19999, "199.99"},
+		{-19999, "-199.99"},
+		{math.MaxInt64, "92233720368547758.07"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q; want %q", tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{19905})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":199.05}` {
+		t.Errorf("Marshal = %s; want {\"amount\":199.05}", data)
+	}
+
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{`199.05`, 19905, nil},
+		{`"199.05"`, 19905, nil},
+		{`-0.5`, -50, nil},
+		{`null`, 0, nil},
+		{`1.005`, 0, ErrPrecision},
+		{`"abc"`, 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestLegacyAmountJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{`199.98000000000002`, 19998, nil},
+		{`"0.30000000000000004"`, 30, nil},
+		{`1.005`, 101, nil},
+		{`-1.005`, -101, nil},
+		{`199.05`, 19905, nil},
+		{`null`, 0, nil},
+		{`"abc"`, 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(tt.in), (*LegacyAmount)(&got))
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v; want %d, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Amount
+		err  error
+	}{
+		{[]byte("12.30"), 1230, nil},
+		{"-12.30", -1230, nil},
+		{int64(12), 1200, nil},
+		{int64(math.MaxInt64), 0, ErrOverflow},
+		// Legacy float columns are rounded to the nearest minor unit.
+		{0.1 + 0.2, 30, nil},
+		{19.999999999999996, 2000, nil},
+		{-0.005, -1, nil},
+		{nil, 0, nil},
+		{true, 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := got.Scan(tt.src)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Scan(%v) = %d, %v; want %d, %v", tt.src, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Currency
+		err  error
+	}{
+		{"usd", "USD", nil},
+		{" EUR ", "EUR", nil},
+		{"US", "", ErrInvalidCurrency},
+		{"US1", "", ErrInvalidCurrency},
+		{"", "", ErrInvalidCurrency},
+	}
+	for _, tt := range tests {
+		got, err := ParseCurrency(tt.in)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ParseCurrency(%q) = %q, %v; want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
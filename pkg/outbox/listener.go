@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the channel notified by the outbox_messages insert trigger.
+const Channel = "outbox_messages"
+
+// Wakeups hands out channels that receive a value whenever outbox messages
+// may have been saved.
+type Wakeups interface {
+	// Subscribe returns a channel of its own and a function that cancels
+	// the subscription.
+	Subscribe() (<-chan struct{}, func())
+}
+
+// Listener turns notifications of new outbox messages into wakeups of the
+// processor workers, so that messages are published right after commit
+// instead of on the next poll. Every subscriber is woken up on each
+// notification.
+type Listener struct {
+	listener *pq.Listener
+
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+// NewListener listens on Channel over a dedicated connection to dsn. The
+// connection is re-established automatically if it drops.
+func NewListener(dsn string) (*Listener, error) {
+	l := &Listener{
+		listener: pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Outbox listener: %v", err)
+			}
+		}),
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+	if err := l.listener.Listen(Channel); err != nil {
+		l.listener.Close()
+		return nil, err
+	}
+	go l.run()
+	return l, nil
+}
+
+// Subscribe implements Wakeups.
+func (l *Listener) Subscribe() (<-chan struct{}, func()) {
+	// Непрочитанное пробуждение уже означает «есть работа», остальные можно отбросить
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	l.subscribers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subscribers, ch)
+		l.mu.Unlock()
+	}
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+func (l *Listener) run() {
+	for {
+		select {
+		case _, ok := <-l.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil приходит после переподключения: уведомления могли потеряться,
+			// поэтому воркеры будятся и в этом случае
+			l.notify()
+		case <-time.After(90 * time.Second):
+			// Простаивающее соединение проверяется, чтобы обрыв не остался незамеченным
+			go l.listener.Ping()
+		}
+	}
+}
+
+// notify wakes up all subscribers without blocking.
+func (l *Listener) notify() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ch := range l.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
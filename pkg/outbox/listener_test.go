@@ -0,0 +1,33 @@
+package outbox
+
+import "testing"
+
+func TestListenerWakesEverySubscriber(t *testing.T) {
+	l := &Listener{subscribers: make(map[chan struct{}]struct{})}
+	first, cancelFirst := l.Subscribe()
+	second, cancelSecond := l.Subscribe()
+	defer cancelSecond()
+
+	l.notify()
+	l.notify() // merged into the pending wakeup, must not block
+	for i, ch := range []<-chan struct{}{first, second} {
+		select {
+		case <-ch:
+		default:
+			t.Errorf("subscriber %d was not woken up", i)
+		}
+	}
+
+	cancelFirst()
+	l.notify()
+	select {
+	case <-first:
+		t.Error("cancelled subscriber was woken up")
+	default:
+	}
+	select {
+	case <-second:
+	default:
+		t.Error("subscriber was not woken up after another cancelled")
+	}
+}
@@ -0,0 +1,58 @@
+// Package outbox relays messages of the transactional outbox to a message
+// broker.
+//
+// Services save messages to their outbox_messages table in the transaction
+// that changes their state; a Processor then claims them with a lease,
+// publishes them and records the outcome. The claim SQL is shared through
+// PostgresRepository, which service repositories embed next to their own
+// Save. A Listener wakes the processor's workers as soon as a message is
+// inserted. The package lives in the shared pkg module so that every service
+// relays the same way.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AggregateOrder is the aggregate type of messages about an order and the
+// returns and shipments that belong to it.
+const AggregateOrder = "order"
+
+// ErrClaimLost is returned when a relay finishes a message whose lease has
+// run out and which another relay has claimed or finished since.
+var ErrClaimLost = errors.New("outbox message is no longer claimed by this relay")
+
+// Message is an event waiting to be published. Messages of the same
+// aggregate are published in the order they were saved and share a Kafka key,
+// so they land on one partition in that order.
+type Message struct {
+	ID            uuid.UUID       `json:"id"`
+	Seq           int64           `json:"seq"` // Assigned on insert; orders messages of one aggregate
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type,omitempty"`
+	AggregateID   string          `json:"aggregate_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`                 // Failed publish attempts so far
+	CorrelationID string          `json:"correlation_id,omitempty"` // Request that caused the message, if any
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Repository is the relay side of an outbox table.
+type Repository interface {
+	ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*Message, error)
+	MarkAsProcessed(ctx context.Context, id uuid.UUID, owner string) error
+	RecordFailure(ctx context.Context, id uuid.UUID, owner string, lastError string, retryAt *time.Time) error
+	ReleaseClaims(ctx context.Context, owner string, ids []uuid.UUID) error
+}
+
+// Publisher sends a message to the broker.
+type Publisher interface {
+	Publish(ctx context.Context, message *Message) error
+}
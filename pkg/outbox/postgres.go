@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PostgresRepository implements Repository over the outbox_messages table.
+// Service repositories embed it and add Save, which writes messages within
+// their own transactions.
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// ClaimPending returns up to limit of the oldest pending messages that are due
+// for an attempt and leases them to owner for the given time. Rows claimed by
+// another relay are skipped rather than waited for, so relays never block each
+// other; a message whose relay dies is claimed again once its lease expires.
+//
+// Only the oldest pending message of each aggregate can be claimed: the next
+// one becomes claimable once it is processed or failed, which keeps the
+// aggregate's messages in order across batches and relays.
+func (r *PostgresRepository) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*Message, error) {
+	query := `
+		WITH due AS (
+			SELECT m.id
+			FROM outbox_messages m
+			WHERE m.status = 'pending' AND m.next_attempt_at <= NOW()
+				AND (m.locked_until IS NULL OR m.locked_until < NOW())
+				AND (m.aggregate_id = '' OR NOT EXISTS (
+					SELECT 1
+					FROM outbox_messages e
+					WHERE e.status = 'pending' AND e.aggregate_type = m.aggregate_type
+						AND e.aggregate_id = m.aggregate_id AND e.seq < m.seq
+				))
+			ORDER BY m.seq
+			LIMIT $2
+			FOR UPDATE OF m SKIP LOCKED
+		)
+		UPDATE outbox_messages m
+		SET locked_by = $1, locked_until = NOW() + make_interval(secs => $3)
+		FROM due
+		WHERE m.id = due.id
+		RETURNING m.id, m.seq, m.type, m.aggregate_type, m.aggregate_id, m.payload, m.status, m.attempts,
+			m.correlation_id, m.created_at, m.updated_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, owner, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		msg := &Message{}
+		err := rows.Scan(
+			&msg.ID,
+			&msg.Seq,
+			&msg.Type,
+			&msg.AggregateType,
+			&msg.AggregateID,
+			&msg.Payload,
+			&msg.Status,
+			&msg.Attempts,
+			&msg.CorrelationID,
+			&msg.CreatedAt,
+			&msg.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING не сохраняет порядок подзапроса
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Seq < messages[j].Seq
+	})
+	return messages, nil
+}
+
+// MarkAsProcessed marks a message claimed by owner as published and ends the
+// lease. It returns ErrClaimLost if owner no longer holds the message.
+func (r *PostgresRepository) MarkAsProcessed(ctx context.Context, id uuid.UUID, owner string) error {
+	query := `
+		UPDATE outbox_messages
+		SET status = 'processed', updated_at = $1, locked_by = NULL, locked_until = NULL
+		WHERE id = $2 AND locked_by = $3
+	`
+
+	res, err := r.db.ExecContext(ctx, query, time.Now(), id, owner)
+	if err != nil {
+		return err
+	}
+	return claimHeld(res)
+}
+
+// RecordFailure counts a failed publish attempt of a message claimed by owner
+// and ends the lease. The message is retried at retryAt, or marked failed if
+// retryAt is nil. It returns ErrClaimLost if owner no longer holds the message.
+func (r *PostgresRepository) RecordFailure(ctx context.Context, id uuid.UUID, owner string, lastError string, retryAt *time.Time) error {
+	query := `
+		UPDATE outbox_messages
+		SET attempts = attempts + 1, last_error = $2,
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE status END,
+			next_attempt_at = COALESCE($3::timestamptz, next_attempt_at),
+			updated_at = $4, locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND locked_by = $5
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, lastError, retryAt, time.Now(), owner)
+	if err != nil {
+		return err
+	}
+	return claimHeld(res)
+}
+
+// ReleaseClaims ends owner's lease on the messages so that they can be
+// claimed again right away. Messages since claimed by another relay are left alone.
+func (r *PostgresRepository) ReleaseClaims(ctx context.Context, owner string, ids []uuid.UUID) error {
+	query := `
+		UPDATE outbox_messages
+		SET locked_by = NULL, locked_until = NULL
+		WHERE id = ANY($1) AND locked_by = $2 AND status = 'pending'
+	`
+
+	_, err := r.db.ExecContext(ctx, query, pq.Array(ids), owner)
+	return err
+}
+
+// claimHeld returns ErrClaimLost if an update of a claimed message matched
+// nothing: the lease ran out and another relay claimed or finished the message.
+func claimHeld(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
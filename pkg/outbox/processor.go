@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config holds the relay settings of the processor.
+type Config struct {
+	// BatchSize is how many messages a worker claims at once.
+	BatchSize int
+	// Workers is how many batches a processor publishes concurrently.
+	Workers int
+	// Lease is how long claimed messages are hidden from other relays. A relay
+	// that dies mid-publish leaves its messages claimed until the lease runs
+	// out, after which any relay picks them up again. It should comfortably
+	// exceed the time to publish a whole batch.
+	Lease time.Duration
+	// PollInterval is how often idle workers look for messages when nothing
+	// wakes them up. New messages wake them at once; retries that fall due and
+	// expired leases are only noticed by polling.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a message is published before it is
+	// marked failed and left for an operator.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles with every
+	// further attempt up to MaxBackoff, with random jitter on top.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultConfig relays with one worker; raise Workers or run more replicas
+// to drain a larger backlog.
+var DefaultConfig = Config{
+	BatchSize:    20,
+	Workers:      1,
+	Lease:        time.Minute,
+	PollInterval: 5 * time.Second,
+	MaxAttempts:  10,
+	BaseBackoff:  time.Second,
+	MaxBackoff:   10 * time.Minute,
+}
+
+// Processor relays pending outbox messages to the broker. Messages are
+// claimed with a lease before they are published, so any number of
+// processors, in one or many service instances, can share the backlog
+// without publishing a message twice while its lease holds.
+type Processor struct {
+	repo      Repository
+	publisher Publisher
+	cfg       Config
+	owner     string
+	wakeups   Wakeups
+}
+
+// NewProcessor returns a processor whose idle workers also wake up on
+// wakeups, such as a Listener of the notifications of new messages from the
+// database. Every worker subscribes on its own, so one notification wakes
+// them all. Nil wakeups leave the processor to polling alone.
+func NewProcessor(repo Repository, publisher Publisher, cfg Config, wakeups Wakeups) *Processor {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultConfig.Workers
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = DefaultConfig.Lease
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig.PollInterval
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultConfig.BaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = max(DefaultConfig.MaxBackoff, cfg.BaseBackoff)
+	}
+	return &Processor{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+		owner:     newOwnerID(),
+		wakeups:   wakeups,
+	}
+}
+
+// Start runs the workers until ctx is cancelled. A worker that claimed
+// anything claims again right away, as a claim holds at most one message of
+// each aggregate; an idle one waits for a wakeup or the next poll.
+func (p *Processor) Start(ctx context.Context) {
+	for i := 0; i < p.cfg.Workers; i++ {
+		go func() {
+			var wakeup <-chan struct{}
+			if p.wakeups != nil {
+				var unsubscribe func()
+				wakeup, unsubscribe = p.wakeups.Subscribe()
+				defer unsubscribe()
+			}
+			for {
+				n, err := p.ProcessPendingMessages(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Printf("Outbox processor error: %v", err)
+				}
+				if err == nil && n > 0 {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-wakeup:
+				case <-time.After(p.cfg.PollInterval):
+				}
+			}
+		}()
+	}
+}
+
+// ProcessPendingMessages claims one batch of due messages and publishes it in
+// order. An aggregate's message is only claimed once every earlier message of
+// it is published or failed for good, so a message waiting for a retry holds
+// back the later messages of its aggregate but nothing else. It returns how
+// many messages were claimed. A message that fails to publish is scheduled
+// for a retry with backoff, or marked failed after MaxAttempts, and the rest
+// of the batch goes on without it. Messages left unpublished after a storage
+// error are released for the next claim.
+func (p *Processor) ProcessPendingMessages(ctx context.Context) (int, error) {
+	claimed := time.Now()
+	messages, err := p.repo.ClaimPending(ctx, p.owner, p.cfg.BatchSize, p.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// С истёкшей арендой сообщения уже может забрать другой экземпляр,
+	// поэтому публикация не должна её пережить
+	leaseCtx, cancel := context.WithDeadline(ctx, claimed.Add(p.cfg.Lease))
+	defer cancel()
+
+	for i, message := range messages {
+		if err := p.publisher.Publish(leaseCtx, message); err != nil {
+			if ctx.Err() != nil {
+				// Остановка сервиса — не ошибка сообщения, попытка не засчитывается
+				p.release(ctx, messages[i:])
+				return len(messages), ctx.Err()
+			}
+			if leaseCtx.Err() != nil {
+				return len(messages), fmt.Errorf("lease of %d outbox messages expired before they were published", len(messages)-i)
+			}
+			if err := p.recordFailure(ctx, message, err); err != nil {
+				p.release(ctx, messages[i:])
+				return len(messages), err
+			}
+			continue
+		}
+		if err := p.repo.MarkAsProcessed(ctx, message.ID, p.owner); err != nil {
+			p.release(ctx, messages[i:])
+			return len(messages), err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// recordFailure schedules the next attempt of a message that failed to
+// publish, or marks it failed once it has used up its attempts.
+func (p *Processor) recordFailure(ctx context.Context, message *Message, publishErr error) error {
+	attempt := message.Attempts + 1
+	var retryAt *time.Time
+	if attempt < p.cfg.MaxAttempts {
+		next := time.Now().Add(p.backoff(attempt))
+		retryAt = &next
+	}
+	if err := p.repo.RecordFailure(ctx, message.ID, p.owner, publishErr.Error(), retryAt); err != nil {
+		return fmt.Errorf("failed to record failed attempt of outbox message %s: %w", message.ID, err)
+	}
+	if retryAt == nil {
+		log.Printf("Outbox message %s (%s) failed after %d attempts, giving up: %v", message.ID, message.Type, attempt, publishErr)
+	} else {
+		log.Printf("Outbox message %s (%s) failed (attempt %d), retrying at %s: %v", message.ID, message.Type, attempt, retryAt.Format(time.RFC3339), publishErr)
+	}
+	return nil
+}
+
+// backoff returns the delay after the given failed attempt. Half of it is
+// random so that messages failed by the same outage are not retried in lockstep.
+func (p *Processor) backoff(attempt int) time.Duration {
+	delay := p.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < p.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.cfg.MaxBackoff {
+		delay = p.cfg.MaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// release hands the messages back so that they are not held until the lease expires.
+func (p *Processor) release(ctx context.Context, messages []*Message) {
+	ids := make([]uuid.UUID, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	if err := p.repo.ReleaseClaims(context.WithoutCancel(ctx), p.owner, ids); err != nil {
+		log.Printf("Failed to release %d outbox messages: %v", len(ids), err)
+	}
+}
+
+// newOwnerID identifies the processor in the locked_by column: the host
+// name, which is the container ID under Docker, plus a random suffix.
+func newOwnerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}